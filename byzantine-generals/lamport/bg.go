@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"flag"
 	"fmt"
 	"log"
 	"math"
@@ -9,6 +10,8 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+
+	"github.com/kulvirs/Concurrency-A2/sched"
 )
 
 // ATTACK represents the command, if it is true, ATTACK, else, RETREAT.
@@ -26,6 +29,62 @@ type Message struct {
 	Round int
 }
 
+// Option configures a run of the generals.
+type Option func(*config)
+
+// Settings for a run of the generals.
+type config struct {
+	// Runs the generals one step at a time when set.
+	scheduler *sched.Scheduler
+}
+
+// WithScheduler runs the generals one step at a time, in the order the scheduler picks, so that a run can be
+// reproduced exactly. Each send and receive is a step, and each general is identified by its index.
+func WithScheduler(s *sched.Scheduler) Option {
+	return func(c *config) {
+		c.scheduler = s
+	}
+}
+
+// Sends a message on the channel as general id, as a step picked by the scheduler if there is one.
+// Returns false if the scheduler stopped before the message could be sent.
+func sendMessage(s *sched.Scheduler, id int, channel chan Message, msg Message) bool {
+	if s == nil {
+		channel <- msg
+		return true
+	}
+	return s.Do(id, func() bool {
+		return len(channel) < cap(channel)
+	}, func() bool {
+		select {
+		case channel <- msg:
+			return true
+		default:
+			return false
+		}
+	})
+}
+
+// Receives a message from the channel as general id, as a step picked by the scheduler if there is one.
+// Returns false if the scheduler stopped before a message arrived.
+func receiveMessage(s *sched.Scheduler, id int, channel chan Message) (Message, bool) {
+	if s == nil {
+		return <-channel, true
+	}
+	var msg Message
+	received := s.Do(id, func() bool {
+		return len(channel) > 0
+	}, func() bool {
+		select {
+		case msg = <-channel:
+			return true
+		default:
+			return false
+		}
+	})
+	return msg, received
+}
+
 // Calculates the majority of the given array of command values and returns it. Default value returned if tied.
 func majority(values []bool) bool {
 	attackCount := 0
@@ -63,7 +122,7 @@ func convertCommand(command bool) string {
 	}
 }
 
-func commander(n int, m int, id int, loyal bool, command bool, channels []chan Message) {
+func commander(n int, m int, id int, loyal bool, command bool, channels []chan Message, s *sched.Scheduler) {
 	for i := 1; i < n; i++ {
 		var msg Message
 		if loyal == false && i%2 == 0 {
@@ -72,19 +131,27 @@ func commander(n int, m int, id int, loyal bool, command bool, channels []chan M
 		} else {
 			msg = Message{id, []int{id}, command, m}
 		}
-		channels[i] <- msg
+		if !sendMessage(s, id, channels[i], msg) {
+			return
+		}
 	}
 }
 
-func lieutenant(n int, m int, id int, loyal bool, channels []chan Message, commands []bool, wg *sync.WaitGroup) {
+func lieutenant(n int, m int, id int, loyal bool, channels []chan Message, commands []bool, s *sched.Scheduler, wg *sync.WaitGroup) {
 	defer wg.Done()
+	if s != nil {
+		defer s.Exit(id)
+	}
 	values := []bool{}
 	numMessages := 1
 	for j := m; j >= 0; j-- {
 		messages := []Message{}
 		for k := 0; k < numMessages; k++ {
 			// receive messages
-			msg := <-channels[id]
+			msg, ok := receiveMessage(s, id, channels[id])
+			if !ok {
+				return
+			}
 			//fmt.Printf("Lieutenant %d received message %v\n", id, msg)
 			values = append(values, msg.Value)
 			msg.Prev = append(msg.Prev, id)
@@ -103,7 +170,9 @@ func lieutenant(n int, m int, id int, loyal bool, channels []chan Message, comma
 						} else {
 							newMsg = Message{id, message.Prev, message.Value, message.Round - 1}
 						}
-						channels[i] <- newMsg
+						if !sendMessage(s, id, channels[i], newMsg) {
+							return
+						}
 					}
 				}
 			}
@@ -116,7 +185,16 @@ func lieutenant(n int, m int, id int, loyal bool, channels []chan Message, comma
 	//fmt.Printf("Lieutenant %d: %s\n", id, majorityValue)
 }
 
-func runGenerals(m int, generals []bool, commOrder bool) []bool {
+// Runs the byzantine generals simulation with the given inputs.
+// m is the number of traitors, generals holds whether each general is loyal with the commander at index 0,
+// and commOrder is the order the commander relays to the lieutenants, true = ATTACK, false = RETREAT.
+// Returns an array containing the final command made by each lieutenant i at index i.
+func runGenerals(m int, generals []bool, commOrder bool, options ...Option) []bool {
+	runConfig := config{}
+	for _, option := range options {
+		option(&runConfig)
+	}
+	s := runConfig.scheduler
 	var wg sync.WaitGroup
 	n := len(generals)
 	wg.Add(n - 1)
@@ -130,23 +208,31 @@ func runGenerals(m int, generals []bool, commOrder bool) []bool {
 	// Create array to store final commands from generals.
 	commands := make([]bool, n)
 
-	for i, loyal := range generals {
-		if i == 0 {
-			// Get the commander to send out initial commands.
-			commander(n, m, i, loyal, commOrder, channels)
-		} else {
-			// Create a goroutine for each lieutenant.
-			go lieutenant(n, m, i, loyal, channels, commands, &wg)
+	if s != nil {
+		// The scheduler must know of every general before any of them takes a step.
+		for i := range generals {
+			s.Start(i)
 		}
 	}
+	for i := 1; i < n; i++ {
+		// Create a goroutine for each lieutenant.
+		go lieutenant(n, m, i, generals[i], channels, commands, s, &wg)
+	}
+	// Get the commander to send out initial commands.
+	commander(n, m, 0, generals[0], commOrder, channels, s)
+	if s != nil {
+		s.Exit(0)
+	}
 
 	wg.Wait()
 	return commands
 }
 
 func main() {
+	seed := flag.Int64("seed", 0, "run the generals one step at a time in an order picked with this seed, and print the schedule (0 leaves the order to the Go runtime)")
+	schedule := flag.String("schedule", "", "run the generals one step at a time following the schedule in this file, as printed by -seed")
+	flag.Parse()
 	scanner := bufio.NewScanner(os.Stdin)
-	var wg sync.WaitGroup
 
 	lines := []string{}
 	for scanner.Scan() {
@@ -163,48 +249,42 @@ func main() {
 		log.Fatal(err)
 	}
 
-	generals := strings.Split(lines[1], " ")
-
-	// Number of generals
-	n := len(generals)
-	wg.Add(n - 1)
-
-	// Order sent out by the commander.
-	var cOrder bool
-	if lines[2] == "ATTACK" {
-		cOrder = true
-	} else {
-		cOrder = false
-	}
-
-	// Create channels to communicate between generals.
-	channels := []chan Message{}
-	for range generals {
-		channels = append(channels, make(chan Message, int(math.Pow(float64(n), float64(m)))))
+	// Whether each general is loyal, with the commander first.
+	generals := []bool{}
+	for _, general := range strings.Split(lines[1], " ") {
+		generalInfo := strings.Split(general, ":")
+		generals = append(generals, generalInfo[1] == "L")
 	}
 
-	// Create array to store final commands from generals.
-	commands := make([]bool, n)
+	// Order sent out by the commander.
+	cOrder := lines[2] == "ATTACK"
 
-	for i, general := range generals {
-		generalInfo := strings.Split(general, ":")
-		var loyal bool
-		if generalInfo[1] == "L" {
-			loyal = true
-		} else {
-			loyal = false
+	options := []Option{}
+	var s *sched.Scheduler
+	if *schedule != "" {
+		text, err := os.ReadFile(*schedule)
+		if err != nil {
+			log.Fatal(err)
 		}
+		steps, err := sched.ParseSchedule(string(text))
+		if err != nil {
+			log.Fatal(err)
+		}
+		s = sched.NewReplay(steps)
+	} else if *seed != 0 {
+		s = sched.New(*seed)
+	}
+	if s != nil {
+		options = append(options, WithScheduler(s))
+	}
 
-		if i == 0 {
-			// Get the commander to send out initial commands.
-			commander(n, m, i, loyal, cOrder, channels)
-		} else {
-			// Create a goroutine for the lieutenant
-			go lieutenant(n, m, i, loyal, channels, commands, &wg)
+	commands := runGenerals(m, generals, cOrder, options...)
+	if s != nil {
+		fmt.Fprintln(os.Stderr, sched.FormatSchedule(s.Schedule()))
+		if err := s.Err(); err != nil {
+			log.Fatal(err)
 		}
 	}
-	wg.Wait()
-	wg.Wait()
 	for i, command := range commands[1:] {
 		fmt.Printf("Lieutenant %d: %s\n", i+1, convertCommand(command))
 	}
@@ -3,8 +3,11 @@ package main
 import (
 	"fmt"
 	"math/rand"
+	"reflect"
 	"testing"
 	"time"
+
+	"github.com/kulvirs/Concurrency-A2/sched"
 )
 
 // Records the percentage of runs that are successful for varying values of m when the commander is loyal.
@@ -103,3 +106,23 @@ func TestTraitorCommander(t *testing.T) {
 		fmt.Printf("%0.2f%% trials successful for m = %d, n = %d\n", 100*(float64(numSuccess)/float64(numTrials)), m, n)
 	}
 }
+
+// Tests that two runs with the same seed take the same steps, and that following the schedule reproduces the run.
+func TestSchedulerReplay(t *testing.T) {
+	generals := []bool{false, true, true, false}
+	first := sched.New(3)
+	commands := runGenerals(1, generals, ATTACK, WithScheduler(first))
+	second := sched.New(3)
+	runGenerals(1, generals, ATTACK, WithScheduler(second))
+	if !reflect.DeepEqual(first.Schedule(), second.Schedule()) {
+		t.Errorf("Expected schedule %v but got %v", first.Schedule(), second.Schedule())
+	}
+	replay := sched.NewReplay(first.Schedule())
+	replayed := runGenerals(1, generals, ATTACK, WithScheduler(replay))
+	if replay.Err() != nil {
+		t.Fatalf("Expected no error but got %v", replay.Err())
+	}
+	if !reflect.DeepEqual(commands, replayed) {
+		t.Errorf("Expected commands %v but got %v", commands, replayed)
+	}
+}
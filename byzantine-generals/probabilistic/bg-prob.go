@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"flag"
 	"fmt"
 	"log"
 	"math/rand"
@@ -10,11 +11,98 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/kulvirs/Concurrency-A2/sched"
 )
 
 // ATTACK represents the command, if it is true, ATTACK, else, RETREAT.
 const ATTACK = true
 
+// The channels closed by a step of the scheduler in the current run, so that a receive from one can tell that
+// it will not block without taking the step. Only steps and their checks, which the scheduler runs one at a time,
+// use it.
+var closedChannels map[chan bool]bool
+
+// Option configures a run of the generals.
+type Option func(*config)
+
+// Settings for a run of the generals.
+type config struct {
+	// Runs the generals one step at a time when set.
+	scheduler *sched.Scheduler
+	// Seeds the global coin flips, 0 to seed them with the current time.
+	seed int64
+}
+
+// WithScheduler runs the generals one step at a time, in the order the scheduler picks, so that a run can be
+// reproduced exactly along with WithSeed. Each send, receive and close is a step, and each general is
+// identified by its index.
+func WithScheduler(s *sched.Scheduler) Option {
+	return func(c *config) {
+		c.scheduler = s
+	}
+}
+
+// WithSeed seeds the global coin flips, so the same seed flips the same coins every time.
+func WithSeed(seed int64) Option {
+	return func(c *config) {
+		c.seed = seed
+	}
+}
+
+// Sends a value on the channel as general id, as a step picked by the scheduler if there is one.
+// Returns false if the scheduler stopped before the value could be sent.
+func put(s *sched.Scheduler, id int, channel chan bool, value bool) bool {
+	if s == nil {
+		channel <- value
+		return true
+	}
+	return s.Do(id, func() bool {
+		return len(channel) < cap(channel)
+	}, func() bool {
+		select {
+		case channel <- value:
+			return true
+		default:
+			return false
+		}
+	})
+}
+
+// Receives a value from the channel as general id, as a step picked by the scheduler if there is one.
+// more is false if the channel has been closed, and ok is false if the scheduler stopped before a value arrived.
+func take(s *sched.Scheduler, id int, channel chan bool) (value bool, more bool, ok bool) {
+	if s == nil {
+		value, more = <-channel
+		return value, more, true
+	}
+	ok = s.Do(id, func() bool {
+		return len(channel) > 0 || closedChannels[channel]
+	}, func() bool {
+		select {
+		case value, more = <-channel:
+			return true
+		default:
+			return false
+		}
+	})
+	return value, more, ok
+}
+
+// Closes the channel as general id, as a step picked by the scheduler if there is one.
+// Returns false if the scheduler stopped first.
+func shut(s *sched.Scheduler, id int, channel chan bool) bool {
+	if s == nil {
+		close(channel)
+		return true
+	}
+	return s.Do(id, nil, func() bool {
+		close(channel)
+		closedChannels[channel] = true
+		return true
+	})
+}
+
 // Calculates the majority of the given array of command values and returns either "ATTACK", "RETREAT", or "TIE" accordingly.
 func majority(values []bool) (bool, int) {
 	attackCount := 0
@@ -52,33 +140,42 @@ func convertCommand(command bool) string {
 }
 
 // Sends a command on the given channel. Flips the command if the sender is a traitor and sending to an even-valued general.
-func send(channel chan bool, sender int, receiver int, command bool, loyal bool) {
+// Returns false if the scheduler stopped before the command could be sent.
+func send(s *sched.Scheduler, channel chan bool, sender int, receiver int, command bool, loyal bool) bool {
 	if loyal == false && receiver%2 == 0 {
 		// Traitor general sending to an even-valued general flips the command.
-		channel <- !command
-	} else {
-		channel <- command
+		return put(s, sender, channel, !command)
 	}
+	return put(s, sender, channel, command)
 }
 
-func commander(n int, m int, id int, loyal bool, command bool, channels []chan bool, wg *sync.WaitGroup) {
-	// Generate a global coin flip value.
+func commander(n int, m int, id int, loyal bool, command bool, channels []chan bool, coin *rand.Rand, s *sched.Scheduler, wg *sync.WaitGroup) {
 	defer wg.Done()
-	rand.Seed(time.Now().UnixNano())
-	coinFlip := rand.Intn(2) == 0
+	if s != nil {
+		defer s.Exit(id)
+	}
+	// Generate a global coin flip value.
+	coinFlip := coin.Intn(2) == 0
 
 	// Send out initial command to all nodes.
 	for i := 1; i < n; i++ {
-		send(channels[i], id, i, command, loyal)
+		if !send(s, channels[i], id, i, command, loyal) {
+			return
+		}
 		// Send global coin flip value to all nodes.
-		channels[i] <- coinFlip
+		if !put(s, id, channels[i], coinFlip) {
+			return
+		}
 	}
 
 	for {
 		// Receive each node's value.
 		values := []bool{}
 		for i := 1; i < n; i++ {
-			value := <-channels[id]
+			value, _, ok := take(s, id, channels[id])
+			if !ok {
+				return
+			}
 			values = append(values, value)
 		}
 
@@ -87,42 +184,57 @@ func commander(n int, m int, id int, loyal bool, command bool, channels []chan b
 		if tally >= (2*m)+1 {
 			// No more rounds.
 			for i := 1; i < n; i++ {
-				close(channels[i])
+				if !shut(s, id, channels[i]) {
+					return
+				}
 			}
 			break
 		} else {
 			// Not all loyal nodes are in agreement. Run another round with a new global coin flip value.
-			coinFlip = rand.Intn(2) == 0
+			coinFlip = coin.Intn(2) == 0
 			for i := 1; i < n; i++ {
-				channels[i] <- coinFlip
+				if !put(s, id, channels[i], coinFlip) {
+					return
+				}
 			}
 		}
 	}
 }
 
-func lieutenant(n int, m int, id int, loyal bool, commChannels []chan bool, channels []chan bool, commands []bool, wg *sync.WaitGroup) {
+func lieutenant(n int, m int, id int, loyal bool, commChannels []chan bool, channels []chan bool, commands []bool, s *sched.Scheduler, wg *sync.WaitGroup) {
 	defer wg.Done()
+	if s != nil {
+		defer s.Exit(id)
+	}
 
 	// Get initial command from commander.
-	command := <-commChannels[id]
+	command, _, ok := take(s, id, commChannels[id])
+	if !ok {
+		return
+	}
 	//fmt.Printf("Lieutenant %d received message from commander with command %v\n", id, convertCommand(command))
 
 	for {
-		coinFlip, more := <-commChannels[id]
-		if more == false {
+		coinFlip, more, ok := take(s, id, commChannels[id])
+		if !ok || more == false {
 			// End of algorithm
 			break
 		}
 
 		// Send command to all other lieutenants.
 		for i := 1; i < n; i++ {
-			send(channels[i], id, i, command, loyal)
+			if !send(s, channels[i], id, i, command, loyal) {
+				return
+			}
 		}
 
 		// Receive commands from all other lieutenants.
 		values := []bool{}
 		for i := 1; i < n; i++ {
-			value := <-channels[id]
+			value, _, ok := take(s, id, channels[id])
+			if !ok {
+				return
+			}
 			values = append(values, value)
 		}
 
@@ -135,7 +247,9 @@ func lieutenant(n int, m int, id int, loyal bool, commChannels []chan bool, chan
 		}
 
 		// Send majority value back to commander.
-		send(commChannels[0], id, 0, command, loyal)
+		if !send(s, commChannels[0], id, 0, command, loyal) {
+			return
+		}
 		// Update the entry for this node in the array of commands.
 		commands[id] = command
 	}
@@ -146,7 +260,18 @@ func lieutenant(n int, m int, id int, loyal bool, commChannels []chan bool, chan
 // generals is an array of generals with index 0 being the commander. The value at each index, i, is true if general i is loyal, false otherwise.
 // commOrder is the order that the commander will relay to the lieutenants, true = ATTACK, false = RETREAT.
 // Returns an array containing the final command made by each lieutenant i at index i.
-func runGenerals(m int, generals []bool, commOrder bool) []bool {
+func runGenerals(m int, generals []bool, commOrder bool, options ...Option) []bool {
+	runConfig := config{}
+	for _, option := range options {
+		option(&runConfig)
+	}
+	s := runConfig.scheduler
+	closedChannels = map[chan bool]bool{}
+	seed := runConfig.seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	coin := rand.New(rand.NewSource(seed))
 	var wg sync.WaitGroup
 	n := len(generals)
 	wg.Add(n)
@@ -166,13 +291,19 @@ func runGenerals(m int, generals []bool, commOrder bool) []bool {
 	// This stores the final command at each node by the end of the algorithm.
 	commands := make([]bool, n)
 
+	if s != nil {
+		// The scheduler must know of every general before any of them takes a step.
+		for i := range generals {
+			s.Start(i)
+		}
+	}
 	for i, loyal := range generals {
 		if i == 0 {
 			// Get the commander to send out initial commands.
-			go commander(n, m, i, loyal, commOrder, commChannels, &wg)
+			go commander(n, m, i, loyal, commOrder, commChannels, coin, s, &wg)
 		} else {
 			// Create a goroutine for each lieutenant.
-			go lieutenant(n, m, i, loyal, commChannels, channels, commands, &wg)
+			go lieutenant(n, m, i, loyal, commChannels, channels, commands, s, &wg)
 		}
 	}
 	wg.Wait()
@@ -180,8 +311,13 @@ func runGenerals(m int, generals []bool, commOrder bool) []bool {
 }
 
 func main() {
+	seed := flag.Int64("seed", 0, "flip the coins with this seed and run the generals one step at a time in an order picked with it, printing the schedule (0 leaves both to chance)")
+	schedule := flag.String("schedule", "", "run the generals one step at a time following the schedule in this file, as printed by -seed, with the coins flipped by -seed")
+	flag.Parse()
+	if *schedule != "" && *seed == 0 {
+		log.Fatal("-schedule needs the -seed of the run it was printed by, to flip the same coins")
+	}
 	scanner := bufio.NewScanner(os.Stdin)
-	var wg sync.WaitGroup
 
 	lines := []string{}
 	for scanner.Scan() {
@@ -201,53 +337,42 @@ func main() {
 		log.Fatal(err)
 	}
 
-	generals := strings.Split(lines[1], " ")
-
-	// Number of generals
-	n := len(generals)
-	wg.Add(n)
-
-	// Order sent out by the commander.
-	var cOrder bool
-	if lines[2] == "ATTACK" {
-		cOrder = true
-	} else {
-		cOrder = false
+	// Whether each general is loyal, with the commander first.
+	generals := []bool{}
+	for _, general := range strings.Split(lines[1], " ") {
+		generalInfo := strings.Split(general, ":")
+		generals = append(generals, generalInfo[1] == "L")
 	}
 
-	// Create channels to communicate between generals.
-	channels := []chan bool{}
-	for range generals {
-		channels = append(channels, make(chan bool, n))
+	// Order sent out by the commander.
+	cOrder := lines[2] == "ATTACK"
+
+	options := []Option{WithSeed(*seed)}
+	var s *sched.Scheduler
+	if *schedule != "" {
+		text, err := os.ReadFile(*schedule)
+		if err != nil {
+			log.Fatal(err)
+		}
+		steps, err := sched.ParseSchedule(string(text))
+		if err != nil {
+			log.Fatal(err)
+		}
+		s = sched.NewReplay(steps)
+	} else if *seed != 0 {
+		s = sched.New(*seed)
 	}
-
-	//Create channels used to communicate between commander and lieutenants.
-	commChannels := []chan bool{}
-	for range generals {
-		commChannels = append(commChannels, make(chan bool, n))
+	if s != nil {
+		options = append(options, WithScheduler(s))
 	}
 
-	// After the algorithm terminates, this stores the final command made at each node.
-	commands := make([]bool, n)
-
-	for i, general := range generals {
-		generalInfo := strings.Split(general, ":")
-		var loyal bool
-		if generalInfo[1] == "L" {
-			loyal = true
-		} else {
-			loyal = false
-		}
-
-		if i == 0 {
-			// Get the commander to send out initial commands.
-			go commander(n, m, i, loyal, cOrder, commChannels, &wg)
-		} else {
-			// Create a goroutine for the lieutenants.
-			go lieutenant(n, m, i, loyal, commChannels, channels, commands, &wg)
+	commands := runGenerals(m, generals, cOrder, options...)
+	if s != nil {
+		fmt.Fprintln(os.Stderr, sched.FormatSchedule(s.Schedule()))
+		if err := s.Err(); err != nil {
+			log.Fatal(err)
 		}
 	}
-	wg.Wait()
 	for i, command := range commands[1:] {
 		fmt.Printf("Lieutenant %d: %s\n", i+1, convertCommand(command))
 	}
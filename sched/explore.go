@@ -0,0 +1,206 @@
+package sched
+
+import "sort"
+
+// Step is a step taken while exploring, along with the steps every goroutine was waiting to take before it.
+type Step struct {
+	// The goroutine that took the step, or -1 if no step could be taken.
+	ID int
+	// The objects touched by the next step of each waiting goroutine, including ID.
+	Pending map[int][]string
+	// The waiting goroutines whose step was tried and could not be taken.
+	Disabled []int
+}
+
+// Exploration counts the runs tried by Explore.
+type Exploration struct {
+	// The number of runs tried.
+	Runs int
+	// The number of runs abandoned because a goroutine picked to take a step could not take it.
+	Infeasible int
+	// Whether every order of the steps that could change the outcome was tried, false if run stopped early.
+	Complete bool
+}
+
+// The state before a step of the run being explored.
+type exploreNode struct {
+	// The step taken from this state in the current run.
+	step Step
+	// Goroutines whose step should be tried first from this state.
+	backtrack map[int]bool
+	// Goroutines whose step has been tried first from this state.
+	done map[int]bool
+	// Goroutines whose step could not be taken from this state.
+	disabled map[int]bool
+}
+
+// Returns true if two lists of objects share an object, with All shared by every list that is not empty.
+func touchesSame(a []string, b []string) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y || x == All || y == All {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Explore runs a program under every order of its steps that could change its outcome. run runs the program
+// once with the scheduler it is given, and returns false to stop exploring. The first run takes the first step
+// that can be taken by the goroutine with the lowest id, and every later run follows an earlier one up to a
+// step where it tries another goroutine first.
+//
+// Orders are pruned with dynamic partial-order reduction (Flanagan and Godefroid): after each run, every pair of
+// steps of different goroutines that touch the same object and are not already ordered by earlier steps is a
+// race, and another run is scheduled that tries the later goroutine first at the state before the earlier step.
+// Steps are ordered by happens-before, tracked with a vector clock for each goroutine and object.
+func Explore(run func(s *Scheduler) bool) Exploration {
+	exploration := Exploration{}
+	nodes := []*exploreNode{}
+	prefix := []int{}
+	for {
+		s := NewReplay(prefix)
+		s.exploring = true
+		more := run(s)
+		exploration.Runs++
+		trace := s.trace
+		if len(trace) > 0 && trace[len(trace)-1].ID < 0 && len(trace) <= len(prefix) {
+			// The goroutine picked at the last step of the prefix could not take it, so try every other one.
+			exploration.Infeasible++
+			node := nodes[len(trace)-1]
+			for _, id := range trace[len(trace)-1].Disabled {
+				node.disabled[id] = true
+			}
+			for id := range node.step.Pending {
+				if !node.disabled[id] {
+					node.backtrack[id] = true
+				}
+			}
+		} else {
+			for i := len(prefix) - 1; i < len(trace); i++ {
+				if i < 0 {
+					continue
+				}
+				if i == len(nodes) {
+					nodes = append(nodes, &exploreNode{backtrack: map[int]bool{}, done: map[int]bool{}, disabled: map[int]bool{}})
+				}
+				nodes[i].step = trace[i]
+				nodes[i].done[trace[i].ID] = true
+				for _, id := range trace[i].Disabled {
+					nodes[i].disabled[id] = true
+				}
+			}
+			addBacktracks(nodes[:len(trace)])
+			nodes = nodes[:len(trace)]
+		}
+		if !more {
+			return exploration
+		}
+		// Try another goroutine first from the deepest state that has one left.
+		prefix = nil
+		for i := len(nodes) - 1; i >= 0 && prefix == nil; i-- {
+			ids := []int{}
+			for id := range nodes[i].backtrack {
+				if !nodes[i].done[id] && !nodes[i].disabled[id] {
+					ids = append(ids, id)
+				}
+			}
+			if len(ids) == 0 {
+				continue
+			}
+			sort.Ints(ids)
+			nodes = nodes[:i+1]
+			nodes[i].done[ids[0]] = true
+			prefix = make([]int, i+1)
+			for j := 0; j < i; j++ {
+				prefix[j] = nodes[j].step.ID
+			}
+			prefix[i] = ids[0]
+		}
+		if prefix == nil {
+			exploration.Complete = true
+			return exploration
+		}
+	}
+}
+
+// Finds the races in a run and adds the goroutine to try first to the state before the earlier step of each.
+// The last node is the state the run ended in if no step could be taken from it.
+func addBacktracks(nodes []*exploreNode) {
+	// clocks[id][g] is the number of steps up to and including the last step of g that happens before the
+	// next step of goroutine id, counted from the start of the run.
+	clocks := map[int]map[int]int{}
+	objectClocks := map[string]map[int]int{}
+	// The clock of each step once it has been taken.
+	stepClocks := []map[int]int{}
+	for k, node := range nodes {
+		// The next step of every goroutine waiting in this state races with the last step it depends on that
+		// does not happen before it.
+		for id, objects := range node.step.Pending {
+			clock := clocks[id]
+			for i := k - 1; i >= 0; i-- {
+				earlier := nodes[i].step
+				if earlier.ID != id && touchesSame(earlier.Pending[earlier.ID], objects) && clock[earlier.ID] < i+1 {
+					nodes[i].addBacktrack(id, nodes[i+1:k], stepClocks[i+1:k], clock)
+					break
+				}
+			}
+		}
+		if node.step.ID < 0 {
+			break
+		}
+		id := node.step.ID
+		clock := map[int]int{}
+		for g, c := range clocks[id] {
+			clock[g] = c
+		}
+		objects := node.step.Pending[id]
+		for object, objectClock := range objectClocks {
+			if touchesSame([]string{object}, objects) {
+				for g, c := range objectClock {
+					if c > clock[g] {
+						clock[g] = c
+					}
+				}
+			}
+		}
+		clock[id] = k + 1
+		clocks[id] = clock
+		for _, object := range objects {
+			objectClocks[object] = clock
+		}
+		for _, object := range objects {
+			if object == All {
+				// A step that touches every object is ordered before every later step that touches any of them.
+				for other := range objectClocks {
+					objectClocks[other] = clock
+				}
+			}
+		}
+		stepClocks = append(stepClocks, clock)
+	}
+}
+
+// Adds a goroutine to try first from this state so that the step of goroutine id can race ahead of the step
+// taken from it: id itself if it can take a step here, otherwise a goroutine whose later step happens before
+// the step of id, and otherwise every goroutine that may be able to take a step. later holds the states after
+// this one up to the race, and clock is the clock of id's step.
+func (node *exploreNode) addBacktrack(id int, later []*exploreNode, laterClocks []map[int]int, clock map[int]int) {
+	if _, waiting := node.step.Pending[id]; waiting && !node.disabled[id] {
+		node.backtrack[id] = true
+		return
+	}
+	for j, next := range later {
+		g := next.step.ID
+		if _, waiting := node.step.Pending[g]; waiting && !node.disabled[g] && clock[g] >= laterClocks[j][g] {
+			node.backtrack[g] = true
+			return
+		}
+	}
+	for g := range node.step.Pending {
+		if !node.disabled[g] {
+			node.backtrack[g] = true
+		}
+	}
+}
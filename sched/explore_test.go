@@ -0,0 +1,129 @@
+package sched
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// Runs goroutines that each take one step on every object in their list, in order, and returns the order the
+// steps on each object were taken in as a string such as "map[x:[0 1] y:[1]]".
+func runObjects(s *Scheduler, objects [][]string) string {
+	order := map[string][]int{}
+	var wg sync.WaitGroup
+	for id := range objects {
+		s.Start(id)
+	}
+	for id, list := range objects {
+		wg.Add(1)
+		go func(id int, list []string) {
+			defer wg.Done()
+			defer s.Exit(id)
+			for _, object := range list {
+				s.DoOn(id, []string{object}, nil, func() bool {
+					order[object] = append(order[object], id)
+					return true
+				})
+			}
+		}(id, list)
+	}
+	wg.Wait()
+	// Maps are printed with their keys sorted.
+	return fmt.Sprint(order)
+}
+
+// Explores every run of goroutines stepping on the given objects and returns the distinct orders of the
+// steps on the objects, along with the exploration.
+func exploreObjects(objects [][]string) (map[string]bool, Exploration) {
+	outcomes := map[string]bool{}
+	exploration := Explore(func(s *Scheduler) bool {
+		order := runObjects(s, objects)
+		if s.Err() == nil {
+			outcomes[order] = true
+		}
+		return true
+	})
+	return outcomes, exploration
+}
+
+// Tests that steps on the same object are tried in every order.
+func TestExploreDependent(t *testing.T) {
+	outcomes, exploration := exploreObjects([][]string{{"x"}, {"x"}, {"x"}})
+	if !exploration.Complete || len(outcomes) != 6 {
+		t.Errorf("Expected a complete exploration of 6 orders but got %d orders in %+v", len(outcomes), exploration)
+	}
+}
+
+// Tests that independent steps are only tried in one order.
+func TestExploreIndependent(t *testing.T) {
+	outcomes, exploration := exploreObjects([][]string{{"x", "y"}, {"z"}, {"w"}})
+	if !exploration.Complete || exploration.Runs != 1 || len(outcomes) != 1 {
+		t.Errorf("Expected a single run but got %d orders in %+v", len(outcomes), exploration)
+	}
+}
+
+// Tests that only the steps that touch the same object are reordered when the goroutines share some objects.
+func TestExploreMixed(t *testing.T) {
+	outcomes, exploration := exploreObjects([][]string{{"a", "x"}, {"b", "x"}, {"c"}})
+	if !exploration.Complete || len(outcomes) != 2 || exploration.Runs > 3 {
+		t.Errorf("Expected at most 3 runs with 2 orders but got %d orders in %+v", len(outcomes), exploration)
+	}
+}
+
+// Tests that a step that waits on another goroutine is explored, and that a run that cannot follow its schedule
+// is not counted as an outcome.
+func TestExploreBlocked(t *testing.T) {
+	outcomes := map[string]bool{}
+	exploration := Explore(func(s *Scheduler) bool {
+		sent := false
+		order := ""
+		var wg sync.WaitGroup
+		s.Start(0)
+		s.Start(1)
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			defer s.Exit(0)
+			s.DoOn(0, []string{"channel"}, nil, func() bool {
+				sent = true
+				return true
+			})
+			s.DoOn(0, []string{"x"}, nil, func() bool {
+				order += "0x "
+				return true
+			})
+		}()
+		go func() {
+			defer wg.Done()
+			defer s.Exit(1)
+			s.DoOn(1, []string{"channel"}, func() bool {
+				return sent
+			}, func() bool {
+				return sent
+			})
+			s.DoOn(1, []string{"x"}, nil, func() bool {
+				order += "1x "
+				return true
+			})
+		}()
+		wg.Wait()
+		if s.Err() == nil {
+			outcomes[order] = true
+		}
+		return true
+	})
+	if !exploration.Complete || len(outcomes) != 2 {
+		t.Errorf("Expected 2 orders but got %v in %+v", outcomes, exploration)
+	}
+}
+
+// Tests that exploring stops when the run asks it to.
+func TestExploreStop(t *testing.T) {
+	exploration := Explore(func(s *Scheduler) bool {
+		runObjects(s, [][]string{{"x"}, {"x"}})
+		return false
+	})
+	if exploration.Complete || exploration.Runs != 1 {
+		t.Errorf("Expected to stop after a single run but got %+v", exploration)
+	}
+}
@@ -0,0 +1,276 @@
+// Package sched runs a group of goroutines one step at a time, in an order chosen by a seeded random number
+// generator or given by a schedule, so a run of a simulation can be reproduced exactly.
+//
+// A step is a non-blocking attempt at an operation, such as a send or receive on a channel that may be full or
+// empty, along with a check of whether the attempt would succeed that has no side effects. Each goroutine calls Do
+// with its next step and waits there until the scheduler picks it. Once every goroutine is waiting, the scheduler
+// picks one that is ready and takes its attempt, and that goroutine runs on its own until it calls Do again or
+// exits. If no step is ready, every goroutine is blocked and, unless a function set with OnBlocked makes a step
+// possible, Do returns false.
+//
+// Explore runs a program under every order of its steps that could change its outcome, using the objects each
+// step touches to skip orders that only swap independent steps.
+package sched
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Scheduler picks which goroutine takes the next step. Goroutines are identified by an id, such as a process id.
+type Scheduler struct {
+	mutex sync.Mutex
+	cond  *sync.Cond
+	// Picks the order of the steps, nil when following a schedule.
+	rng *rand.Rand
+	// The schedule to follow, nil when picking steps at random.
+	replay []int
+	// Whether to keep going after the schedule ends, taking the first step that can be taken, and record a trace.
+	exploring bool
+	// The steps taken so far when exploring, followed by the step that could not be taken if the schedule
+	// could not be followed.
+	trace []Step
+	// The goroutine that took each step so far.
+	steps []int
+	// Goroutines that have started and not exited.
+	live map[int]bool
+	// The next step of every goroutine waiting in Do.
+	waiting map[int]func() bool
+	// Whether the next step of every goroutine waiting in Do would succeed, nil if it always succeeds.
+	ready map[int]func() bool
+	// The objects touched by the next step of every goroutine waiting in Do.
+	objects map[int][]string
+	// Goroutines whose step was taken, but that have not woken up yet.
+	picked map[int]bool
+	// Set once no more steps can be taken, because every goroutine is blocked or the schedule could not be followed.
+	stopped bool
+	// Set if the schedule could not be followed.
+	err error
+	// Called once every goroutine is blocked, nil if there is nothing to call.
+	unblock func() bool
+}
+
+// Returns a scheduler with no goroutines.
+func newScheduler() *Scheduler {
+	s := &Scheduler{steps: []int{}, live: map[int]bool{}, waiting: map[int]func() bool{}, ready: map[int]func() bool{}, objects: map[int][]string{}, picked: map[int]bool{}}
+	s.cond = sync.NewCond(&s.mutex)
+	return s
+}
+
+// New returns a scheduler that picks each step at random from the goroutines that can take one, using the seed.
+// The same seed picks the same steps every time.
+func New(seed int64) *Scheduler {
+	s := newScheduler()
+	s.rng = rand.New(rand.NewSource(seed))
+	return s
+}
+
+// NewReplay returns a scheduler that follows a schedule, such as one printed by an earlier run.
+// The schedule lists the goroutine that takes each step. A schedule that ends with every goroutine blocked replays a deadlock,
+// and Err reports a schedule that ends while a goroutine could still take a step.
+func NewReplay(schedule []int) *Scheduler {
+	s := newScheduler()
+	s.replay = schedule
+	return s
+}
+
+// Start adds a goroutine to the group. It must be called before the goroutine is started, by the goroutine that
+// starts it, so that the scheduler waits for its first step.
+func (s *Scheduler) Start(id int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.live[id] = true
+}
+
+// Do waits until the scheduler picks goroutine id to take a step, and returns true once attempt has succeeded.
+// Returns false if no more steps can be taken. The step is treated as touching every object.
+// ready reports whether attempt would succeed without taking the step, or is nil if attempt always succeeds.
+func (s *Scheduler) Do(id int, ready func() bool, attempt func() bool) bool {
+	return s.DoOn(id, []string{All}, ready, attempt)
+}
+
+// All is an object that stands for every object, for steps that may touch anything.
+const All = "*"
+
+// DoOn is Do for a step that only touches the given objects, such as the channels or locks it uses. Steps of
+// different goroutines are independent if they touch none of the same objects, so Explore only tries one order
+// of them. A step that touches no objects, such as the start of a goroutine, is independent of every other step.
+func (s *Scheduler) DoOn(id int, objects []string, ready func() bool, attempt func() bool) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.waiting[id] = attempt
+	s.ready[id] = ready
+	s.objects[id] = objects
+	s.pick()
+	for !s.picked[id] && !s.stopped {
+		s.cond.Wait()
+	}
+	if !s.picked[id] {
+		return false
+	}
+	delete(s.picked, id)
+	return true
+}
+
+// OnBlocked sets a function that is called once every goroutine is blocked, before the run is stopped. It can make
+// steps possible, such as by timing out a wait, and returns true if it changed anything, in which case it is called
+// again if every goroutine is still blocked. It is called while every goroutine is waiting in Do, like the steps
+// themselves, and must not call the scheduler.
+func (s *Scheduler) OnBlocked(unblock func() bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.unblock = unblock
+}
+
+// Exit removes a goroutine from the group when it finishes.
+func (s *Scheduler) Exit(id int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.live, id)
+	s.pick()
+}
+
+// Takes the next step once every goroutine is waiting in Do. Must be called while holding the mutex.
+func (s *Scheduler) pick() {
+	if s.stopped || len(s.waiting) == 0 || len(s.waiting) != len(s.live) || len(s.picked) > 0 {
+		return
+	}
+	// What unblocking does only depends on the steps taken so far, so a schedule is replayed the same way.
+	for s.unblock != nil && s.blocked() {
+		if !s.unblock() {
+			break
+		}
+	}
+	step := Step{ID: -1, Pending: map[int][]string{}, Disabled: []int{}}
+	for id, objects := range s.objects {
+		step.Pending[id] = objects
+	}
+	if s.replay != nil && len(s.steps) < len(s.replay) {
+		id := s.replay[len(s.steps)]
+		if attempt, ok := s.waiting[id]; !ok || !s.enabled(id) || !attempt() {
+			s.record(Step{ID: -1, Pending: step.Pending, Disabled: []int{id}})
+			s.stop(fmt.Errorf("step %d of the schedule: goroutine %d cannot take a step", len(s.steps), id))
+			return
+		}
+		step.ID = id
+		s.record(step)
+		s.take(id)
+		return
+	}
+	ids := []int{}
+	for id := range s.waiting {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	if s.replay != nil && !s.exploring {
+		// A schedule that ends with every goroutine blocked replays a deadlock.
+		for _, id := range ids {
+			if s.enabled(id) {
+				s.stop(fmt.Errorf("the schedule ended after %d steps, but the run had not finished", len(s.steps)))
+				return
+			}
+		}
+		s.stop(nil)
+		return
+	}
+	if s.rng != nil {
+		s.rng.Shuffle(len(ids), func(i, j int) {
+			ids[i], ids[j] = ids[j], ids[i]
+		})
+	}
+	for _, id := range ids {
+		if s.enabled(id) && s.waiting[id]() {
+			step.ID = id
+			s.record(step)
+			s.take(id)
+			return
+		}
+		step.Disabled = append(step.Disabled, id)
+	}
+	// Every goroutine is blocked.
+	s.record(step)
+	s.stop(nil)
+}
+
+// Returns true if no waiting goroutine can take its next step. Must be called while holding the mutex.
+func (s *Scheduler) blocked() bool {
+	for id := range s.waiting {
+		if s.enabled(id) {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns whether the next step of waiting goroutine id would succeed, without taking it.
+// Must be called while holding the mutex.
+func (s *Scheduler) enabled(id int) bool {
+	ready := s.ready[id]
+	return ready == nil || ready()
+}
+
+// Adds a step to the trace when exploring. Must be called while holding the mutex.
+func (s *Scheduler) record(step Step) {
+	if s.exploring {
+		s.trace = append(s.trace, step)
+	}
+}
+
+// Records that goroutine id took a step and wakes it up. Must be called while holding the mutex.
+func (s *Scheduler) take(id int) {
+	delete(s.waiting, id)
+	delete(s.ready, id)
+	delete(s.objects, id)
+	s.steps = append(s.steps, id)
+	s.picked[id] = true
+	s.cond.Broadcast()
+}
+
+// Stops the run, waking up every waiting goroutine. Must be called while holding the mutex.
+func (s *Scheduler) stop(err error) {
+	s.stopped = true
+	s.err = err
+	s.cond.Broadcast()
+}
+
+// Err returns the error that stopped the run if the schedule could not be followed, or nil otherwise.
+func (s *Scheduler) Err() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.err
+}
+
+// Schedule returns the goroutine that took each step so far.
+func (s *Scheduler) Schedule() []int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]int{}, s.steps...)
+}
+
+// FormatSchedule returns a schedule in the form "schedule: 0 1 2 1", which ParseSchedule reads back.
+func FormatSchedule(schedule []int) string {
+	steps := make([]string, len(schedule))
+	for i, id := range schedule {
+		steps[i] = strconv.Itoa(id)
+	}
+	return "schedule: " + strings.Join(steps, " ")
+}
+
+// ParseSchedule reads a schedule written by FormatSchedule. The "schedule:" prefix is optional, and the steps can
+// be separated by any white space.
+func ParseSchedule(text string) ([]int, error) {
+	fields := strings.Fields(strings.TrimPrefix(strings.TrimSpace(text), "schedule:"))
+	schedule := make([]int, len(fields))
+	for i, field := range fields {
+		id, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid step %d of the schedule: %s", i, field)
+		}
+		schedule[i] = id
+	}
+	return schedule, nil
+}
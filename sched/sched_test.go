@@ -0,0 +1,144 @@
+package sched
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// Runs three goroutines that each append their id to a shared log twice, one step per append.
+// Returns the log and the schedule that was taken.
+func runLog(s *Scheduler) ([]int, []int) {
+	log := []int{}
+	var wg sync.WaitGroup
+	for id := 0; id < 3; id++ {
+		s.Start(id)
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			defer s.Exit(id)
+			for i := 0; i < 2; i++ {
+				s.Do(id, nil, func() bool {
+					log = append(log, id)
+					return true
+				})
+			}
+		}(id)
+	}
+	wg.Wait()
+	return log, s.Schedule()
+}
+
+// Tests that the same seed always gives the same order, and that the schedule records it.
+func TestSeedReproducible(t *testing.T) {
+	log, schedule := runLog(New(42))
+	if !reflect.DeepEqual(log, schedule) || len(log) != 6 {
+		t.Fatalf("Expected the schedule %v to match the order of the steps %v", schedule, log)
+	}
+	for run := 0; run < 20; run++ {
+		if again, _ := runLog(New(42)); !reflect.DeepEqual(again, log) {
+			t.Errorf("Expected seed 42 to give %v every time, but got %v", log, again)
+		}
+	}
+}
+
+// Tests that a schedule is followed exactly, and that a schedule that cannot be followed is reported.
+func TestReplay(t *testing.T) {
+	schedule := []int{2, 2, 0, 1, 0, 1}
+	if log, _ := runLog(NewReplay(schedule)); !reflect.DeepEqual(log, schedule) {
+		t.Errorf("Expected the steps to follow %v, but got %v", schedule, log)
+	}
+
+	s := NewReplay([]int{0, 0, 0})
+	if log, _ := runLog(s); len(log) != 2 || s.Err() == nil {
+		t.Errorf("Expected a third step of goroutine 0 to be rejected, but got %v and %v", log, s.Err())
+	}
+
+	// The steps left when a schedule ends are checked, but not taken.
+	s = NewReplay([]int{1, 0})
+	if log, _ := runLog(s); !reflect.DeepEqual(log, []int{1, 0}) || s.Err() == nil {
+		t.Errorf("Expected the run to stop after the schedule without taking another step, but got %v and %v", log, s.Err())
+	}
+}
+
+// Tests that goroutines blocked on each other are stopped instead of hanging.
+func TestBlocked(t *testing.T) {
+	s := New(1)
+	channel := make(chan int)
+	results := make([]bool, 2)
+	var wg sync.WaitGroup
+	for id := 0; id < 2; id++ {
+		s.Start(id)
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			defer s.Exit(id)
+			// Both goroutines receive, so neither can take a step.
+			results[id] = s.Do(id, func() bool {
+				return len(channel) > 0
+			}, func() bool {
+				select {
+				case <-channel:
+					return true
+				default:
+					return false
+				}
+			})
+		}(id)
+	}
+	wg.Wait()
+	if results[0] || results[1] || s.Err() != nil {
+		t.Errorf("Expected both goroutines to be stopped without an error, but got %v and %v", results, s.Err())
+	}
+}
+
+// Tests that a function called once every goroutine is blocked can let the run go on.
+func TestOnBlocked(t *testing.T) {
+	s := New(1)
+	channel := make(chan int, 1)
+	calls := 0
+	s.OnBlocked(func() bool {
+		calls++
+		if calls > 1 {
+			return false
+		}
+		channel <- 1
+		return true
+	})
+	results := make([]bool, 2)
+	var wg sync.WaitGroup
+	for id := 0; id < 2; id++ {
+		s.Start(id)
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			defer s.Exit(id)
+			results[id] = s.Do(id, func() bool {
+				return len(channel) > 0
+			}, func() bool {
+				select {
+				case <-channel:
+					return true
+				default:
+					return false
+				}
+			})
+		}(id)
+	}
+	wg.Wait()
+	if results[0] == results[1] || calls != 2 || s.Err() != nil {
+		t.Errorf("Expected one goroutine to receive the value sent once both were blocked, but got %v after %d call(s)", results, calls)
+	}
+}
+
+// Tests that a printed schedule can be read back.
+func TestParseSchedule(t *testing.T) {
+	schedule := []int{0, 1, 2, 10}
+	parsed, err := ParseSchedule(FormatSchedule(schedule) + "\n")
+	if err != nil || !reflect.DeepEqual(parsed, schedule) {
+		t.Errorf("Expected %v, but got %v (%v)", schedule, parsed, err)
+	}
+	if _, err := ParseSchedule("0 1 x"); err == nil {
+		t.Errorf("Expected an invalid step to be rejected, but it was not.")
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kulvirs/Concurrency-A2/vector-clocks/vclock"
+)
+
+// EventID identifies an event in the recorded timelines by its process and its index in that process's timeline.
+type EventID struct {
+	Process int
+	Index   int
+}
+
+// String returns the label of the event, e.g. P1.e3.
+func (id EventID) String() string {
+	return fmt.Sprintf("P%d.e%d", id.Process, id.Index)
+}
+
+// Parses an event label of the form P<process>.e<index> and checks it refers to a recorded event.
+func parseEventID(label string) (EventID, error) {
+	var id EventID
+	var rest string
+	n, _ := fmt.Sscanf(label+" end", "P%d.e%d %s", &id.Process, &id.Index, &rest)
+	if n != 3 || rest != "end" {
+		return id, fmt.Errorf("invalid event label %q, expected the form P<process>.e<index>", label)
+	}
+	if id.Process < 0 || id.Process >= len(allClockValues) || id.Index < 0 || id.Index >= len(allClockValues[id.Process]) {
+		return id, fmt.Errorf("no event %v in the recorded timelines", id)
+	}
+	return id, nil
+}
+
+// Returns the labels of every recorded event, in process order.
+func allEventIDs() []EventID {
+	ids := []EventID{}
+	for i, clockValues := range allClockValues {
+		for j := range clockValues {
+			ids = append(ids, EventID{i, j})
+		}
+	}
+	return ids
+}
+
+// Compares the recorded vector clocks of two events.
+func compareEvents(a EventID, b EventID) vclock.Ordering {
+	return vclock.CompareCounters(allClockValues[a.Process][a.Index], allClockValues[b.Process][b.Index])
+}
+
+// Returns every pair of events on different processes that are concurrent, each pair listed once.
+func concurrentPairs() [][2]EventID {
+	pairs := [][2]EventID{}
+	ids := allEventIDs()
+	for i, a := range ids {
+		for _, b := range ids[i+1:] {
+			if a.Process != b.Process && compareEvents(a, b) == vclock.Concurrent {
+				pairs = append(pairs, [2]EventID{a, b})
+			}
+		}
+	}
+	return pairs
+}
+
+// Prints the happens-before relation, one line per event listing every event it happens before,
+// followed by every pair of concurrent events.
+func printAnalysis() {
+	ids := allEventIDs()
+	fmt.Printf("Happens-before relation:\n")
+	for _, a := range ids {
+		later := []string{}
+		for _, b := range ids {
+			if compareEvents(a, b) == vclock.Before {
+				later = append(later, b.String())
+			}
+		}
+		if len(later) > 0 {
+			fmt.Printf("  %v -> %s\n", a, strings.Join(later, " "))
+		}
+	}
+	fmt.Printf("Concurrent events:\n")
+	for _, pair := range concurrentPairs() {
+		fmt.Printf("  %v || %v\n", pair[0], pair[1])
+	}
+}
+
+// Answers a query of the form "P0.e2 -> P2.e1?", asking whether the first event happens before the second.
+// The answer also gives the actual relationship between the events.
+func answerQuery(query string) (string, error) {
+	fields := strings.Split(strings.TrimSuffix(strings.TrimSpace(query), "?"), "->")
+	if len(fields) != 2 {
+		return "", fmt.Errorf("invalid query %q, expected the form P<process>.e<index> -> P<process>.e<index>?", query)
+	}
+	a, err := parseEventID(strings.TrimSpace(fields[0]))
+	if err != nil {
+		return "", err
+	}
+	b, err := parseEventID(strings.TrimSpace(fields[1]))
+	if err != nil {
+		return "", err
+	}
+
+	ordering := compareEvents(a, b)
+	switch ordering {
+	case vclock.Before:
+		return fmt.Sprintf("%v -> %v? yes", a, b), nil
+	case vclock.After:
+		return fmt.Sprintf("%v -> %v? no (%v -> %v)", a, b, b, a), nil
+	case vclock.Concurrent:
+		return fmt.Sprintf("%v -> %v? no (%v || %v)", a, b, a, b), nil
+	}
+	return fmt.Sprintf("%v -> %v? no (same event)", a, b), nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+)
+
+// Tests that concurrent pairs are found on different processes and ordered pairs are not reported.
+func TestConcurrentPairs(t *testing.T) {
+	p0Events := []string{"P0", "S1"}
+	p1Events := []string{"P1", "R0"}
+	events := [][]string{p0Events, p1Events}
+
+	if err := RunVectorClock(events); err != nil {
+		t.Fatal(err)
+	}
+
+	// P0.e0 and P0.e1 both come before P1.e1, so only the pairs with P1.e0 are concurrent.
+	expected := [][2]EventID{{{0, 0}, {1, 0}}, {{0, 1}, {1, 0}}}
+	pairs := concurrentPairs()
+	if len(pairs) != len(expected) {
+		t.Fatalf("Expected concurrent pairs %v, but got %v", expected, pairs)
+	}
+	for i, pair := range pairs {
+		if pair != expected[i] {
+			t.Errorf("Expected concurrent pair %v, but got %v", expected[i], pair)
+		}
+	}
+}
+
+// Tests that queries are answered with the relationship between the two events.
+func TestAnswerQuery(t *testing.T) {
+	p0Events := []string{"S1", "P0"}
+	p1Events := []string{"P1", "R0", "S2"}
+	p2Events := []string{"P2", "R1"}
+	events := [][]string{p0Events, p1Events, p2Events}
+
+	if err := RunVectorClock(events); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"P0.e0 -> P2.e1?":  "P0.e0 -> P2.e1? yes",
+		"P2.e1->P0.e0":     "P2.e1 -> P0.e0? no (P0.e0 -> P2.e1)",
+		"P0.e1 -> P2.e1?":  "P0.e1 -> P2.e1? no (P0.e1 || P2.e1)",
+		" P1.e1 -> P1.e1?": "P1.e1 -> P1.e1? no (same event)",
+	}
+	for query, answer := range expected {
+		result, err := answerQuery(query)
+		if err != nil {
+			t.Errorf("Expected query %q to be answered, but got %v", query, err)
+		} else if result != answer {
+			t.Errorf("Expected query %q to be answered with %q, but got %q", query, answer, result)
+		}
+	}
+
+	for _, query := range []string{"P0.e0 P2.e1?", "P0.e5 -> P2.e1?", "P3.e0 -> P2.e1?", "P0.e0x -> P2.e1?"} {
+		if _, err := answerQuery(query); err == nil {
+			t.Errorf("Expected query %q to be rejected, but it was not.", query)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/kulvirs/Concurrency-A2/vector-clocks/vclock"
+)
+
+// Bounded-size clocks. Vector clocks grow with the number of processes, while plausible clocks and Bloom clocks
+// keep a fixed number of counters. Both are merged like vector clocks, so they never miss a happened-before
+// relation, but they can report concurrent events as ordered. Their answers are checked against the vector clocks
+// to see how often that happens for a given size.
+
+// Accuracy counts the answers a clock gives to "does a happen before b?" for every ordered pair of events on
+// different processes, against the answers of the vector clocks.
+type Accuracy struct {
+	// The number of ordered pairs of events asked about.
+	Pairs int
+	// The number of pairs where a happens before b by the vector clocks.
+	Ordered int
+	// The number of pairs where the clock answers that a happens before b.
+	Answered int
+	// The number of pairs where the clock answers that a happens before b, but it does not.
+	FalsePositives int
+	// The number of pairs where a happens before b, but the clock does not answer so. Always 0 for clocks that are
+	// merged like vector clocks.
+	Missed int
+}
+
+// Asks whether a happens before b for every ordered pair of events on different processes, comparing the answers
+// of compare, which compares the clocks of two events, with those of the vector clocks.
+func clockAccuracy(compare func(a EventID, b EventID) vclock.Ordering) Accuracy {
+	accuracy := Accuracy{}
+	ids := allEventIDs()
+	for _, a := range ids {
+		for _, b := range ids {
+			if a.Process == b.Process {
+				continue
+			}
+			accuracy.Pairs++
+			ordered := compareEvents(a, b) == vclock.Before
+			answered := compare(a, b) == vclock.Before
+			if ordered {
+				accuracy.Ordered++
+			}
+			if answered {
+				accuracy.Answered++
+			}
+			if answered && !ordered {
+				accuracy.FalsePositives++
+			} else if ordered && !answered {
+				accuracy.Missed++
+			}
+		}
+	}
+	return accuracy
+}
+
+// Returns the accuracy of the plausible clocks recorded in the run.
+func plausibleAccuracy() Accuracy {
+	return clockAccuracy(func(a EventID, b EventID) vclock.Ordering {
+		return allPlausibleValues[a.Process][a.Index].Compare(allPlausibleValues[b.Process][b.Index])
+	})
+}
+
+// Returns the accuracy of the Bloom clocks recorded in the run.
+func bloomAccuracy() Accuracy {
+	return clockAccuracy(func(a EventID, b EventID) vclock.Ordering {
+		return allBloomValues[a.Process][a.Index].Compare(allBloomValues[b.Process][b.Index])
+	})
+}
+
+// Prints how often the clocks described by name answered that one event happens before another, and how many of
+// those answers were wrong.
+func printAccuracy(name string, accuracy Accuracy) {
+	fmt.Printf("%s (vector clocks need %d entries): %d of %d ordered pair(s) of events answered as happened before, %d correctly",
+		name, numProcesses, accuracy.Answered, accuracy.Pairs, accuracy.Ordered-accuracy.Missed)
+	if accuracy.Answered > 0 {
+		fmt.Printf(" and %d false positive(s) (%.1f%% of the answers)", accuracy.FalsePositives, 100*float64(accuracy.FalsePositives)/float64(accuracy.Answered))
+	}
+	fmt.Printf(".\n")
+	if accuracy.Missed > 0 {
+		fmt.Printf("%d pair(s) that happened before were missed.\n", accuracy.Missed)
+	}
+}
+
+// Prints the plausible clock values for each process, followed by their accuracy.
+func printPlausibleValues() {
+	for i, values := range allPlausibleValues {
+		fmt.Printf("%s timeline: ", processTitle(i))
+		for j, value := range values {
+			if j != 0 {
+				fmt.Printf("-> ")
+			}
+			fmt.Printf("%v ", value)
+		}
+		fmt.Printf("\n")
+	}
+	printAccuracy(fmt.Sprintf("Plausible clocks with %d entries", runConfig.plausible), plausibleAccuracy())
+}
+
+// Prints the Bloom clock values for each process, followed by their accuracy.
+func printBloomValues() {
+	for i, values := range allBloomValues {
+		fmt.Printf("%s timeline: ", processTitle(i))
+		for j, value := range values {
+			if j != 0 {
+				fmt.Printf("-> ")
+			}
+			fmt.Printf("%v ", value)
+		}
+		fmt.Printf("\n")
+	}
+	printAccuracy(fmt.Sprintf("Bloom clocks with %d counters and %d hash(es)", runConfig.bloomCells, runConfig.bloomHashes), bloomAccuracy())
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+// Returns a script of n processes, each sending a message to the next one and receiving from the one before.
+func ringEvents(n int) [][]string {
+	events := make([][]string, n)
+	for i := range events {
+		events[i] = []string{"P", fmt.Sprintf("S%d", (i+1)%n), fmt.Sprintf("R%d", (i+n-1)%n), "P"}
+	}
+	return events
+}
+
+// Tests that plausible clocks never miss a happened-before relation, give false positives with fewer entries
+// than processes, and give the same answers as vector clocks with as many entries as processes.
+func TestPlausibleAccuracy(t *testing.T) {
+	events := ringEvents(6)
+	if err := RunVectorClock(events, WithPlausibleClocks(2)); err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	accuracy := plausibleAccuracy()
+	if accuracy.Pairs != 6*5*4*4 || accuracy.Missed != 0 || accuracy.FalsePositives == 0 {
+		t.Errorf("Expected false positives but nothing missed, but got %+v", accuracy)
+	}
+	if accuracy.Answered != accuracy.Ordered+accuracy.FalsePositives {
+		t.Errorf("Expected every answer to be correct or a false positive, but got %+v", accuracy)
+	}
+	if err := RunVectorClock(events, WithPlausibleClocks(6)); err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	if exact := plausibleAccuracy(); exact.FalsePositives != 0 || exact.Answered != accuracy.Ordered {
+		t.Errorf("Expected the same answers as vector clocks but got %+v", exact)
+	}
+}
+
+// Tests that Bloom clocks never miss a happened-before relation, and give fewer false positives with more counters.
+func TestBloomAccuracy(t *testing.T) {
+	events := ringEvents(8)
+	falsePositives := []int{}
+	for _, cells := range []int{1, 64} {
+		if err := RunVectorClock(events, WithBloomClocks(cells, 2)); err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+		accuracy := bloomAccuracy()
+		if accuracy.Missed != 0 {
+			t.Errorf("Expected nothing to be missed with %d counters but got %+v", cells, accuracy)
+		}
+		falsePositives = append(falsePositives, accuracy.FalsePositives)
+	}
+	if falsePositives[0] <= falsePositives[1] {
+		t.Errorf("Expected fewer false positives with more counters but got %v", falsePositives)
+	}
+}
+
+// Tests that forked processes start with their parent's bounded clocks, and that joins merge them.
+func TestBoundedClocksForkJoin(t *testing.T) {
+	events := [][]string{{"F1", "J1", "P"}, {"P"}}
+	if err := RunVectorClock(events, WithPlausibleClocks(1), WithBloomClocks(4, 1)); err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	if child := allPlausibleValues[1][0]; child.String() != "[2]" {
+		t.Errorf("Expected the child to start from its parent's [1] but got %v", child)
+	}
+	if joined := allPlausibleValues[0][1]; joined.String() != "[3]" {
+		t.Errorf("Expected the join to merge the child's [2] but got %v", joined)
+	}
+	if accuracy := bloomAccuracy(); accuracy.Missed != 0 {
+		t.Errorf("Expected nothing to be missed but got %+v", accuracy)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+)
+
+// Causal broadcast in the style of Birman-Schiper-Stephenson. A broadcast is timestamped with the number of
+// broadcasts from each process its sender had delivered. A receiver buffers a broadcast from process j until it
+// has delivered every broadcast from j before this one and every broadcast the sender had delivered from other processes.
+
+// Broadcasts a message to every other process. The broadcast is delivered at the sender straight away.
+// Returns false if the simulation deadlocked before the message could be sent to every process.
+func (state *nodeState) broadcast(e Event, index int) bool {
+	id := state.clock.ID
+	state.tick()
+	state.delivered.Tick()
+	msg := state.message()
+	msg.Broadcast = state.delivered.Copy()
+	msg.Payload = e.Label
+	for dest := 0; dest < numProcesses; dest++ {
+		if dest != id && !send(id, dest, index, msg) {
+			return false
+		}
+	}
+	state.record(e)
+	return true
+}
+
+// Records the receipt of a broadcast and delivers every buffered broadcast that has become deliverable.
+// Receiving a broadcast is an event at the receiver, but its clock is only merged into the receiver's when it is delivered.
+func (state *nodeState) receiveBroadcast(e Event, msg Message) {
+	state.arrive(msg)
+	state.tick()
+	state.record(Event{Type: 'R', Peer: e.Peer, Any: e.Any, Broadcast: true, Label: e.Label, Payload: msg.Payload, Sequence: e.Sequence})
+	state.pending = append(state.pending, msg)
+	for state.deliverNext() {
+	}
+}
+
+// Delivers the first buffered broadcast that is deliverable. Returns false if none are.
+func (state *nodeState) deliverNext() bool {
+	for i, msg := range state.pending {
+		if state.deliverable(msg) {
+			state.pending = append(state.pending[:i], state.pending[i+1:]...)
+			sender := msg.Broadcast.ID
+			state.delivered.Counters[sender]++
+			state.merge(msg)
+			state.record(Event{Type: 'D', Peer: sender, Broadcast: true, Payload: msg.Payload})
+			return true
+		}
+	}
+	return false
+}
+
+// Returns true if msg is the next broadcast from its sender and every broadcast it depends on has been delivered.
+func (state *nodeState) deliverable(msg Message) bool {
+	sender := msg.Broadcast.ID
+	for k, count := range msg.Broadcast.Counters {
+		if k == sender && count != state.delivered.Counters[k]+1 {
+			return false
+		} else if k != sender && count > state.delivered.Counters[k] {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns a label describing a broadcast event in a timeline, or an empty string for any other event.
+func broadcastLabel(e Event) string {
+	switch {
+	case e.Type == 'B':
+		return "broadcast"
+	case e.Type == 'R' && e.Broadcast:
+		return fmt.Sprintf("receive B%d", e.Peer)
+	case e.Type == 'D':
+		return fmt.Sprintf("deliver B%d", e.Peer)
+	}
+	return ""
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"testing"
+)
+
+// Returns the senders of the broadcasts delivered by process id, in the order they were delivered.
+func deliveryOrder(id int) []int {
+	senders := []int{}
+	for _, e := range allTimelineEvents[id] {
+		if e.Type == 'D' {
+			senders = append(senders, e.Peer)
+		}
+	}
+	return senders
+}
+
+// Tests that a broadcast received before a broadcast it causally depends on is buffered until the earlier one is delivered.
+func TestCausalBroadcastBuffered(t *testing.T) {
+	// Process 1 delivers process 0's broadcast before broadcasting, but process 2 receives process 1's broadcast first.
+	p0Events := []string{"B", "R1"}
+	p1Events := []string{"R0", "B"}
+	p2Events := []string{"R1", "R0"}
+	events := [][]string{p0Events, p1Events, p2Events}
+
+	if err := RunVectorClock(events); err != nil {
+		t.Fatal(err)
+	}
+
+	order := deliveryOrder(2)
+	if len(order) != 2 || order[0] != 0 || order[1] != 1 {
+		t.Errorf("Expected process 2 to deliver the broadcast from process 0 before the one from process 1, but got %v", order)
+	}
+	// Process 2's timeline is: receive B1, receive B0, deliver B0, deliver B1.
+	if len(allClockValues[2]) != 4 {
+		t.Fatalf("Expected 2 receipts and 2 deliveries at process 2, but got %v", allTimelineEvents[2])
+	}
+	// The delivery of process 1's broadcast comes after the broadcast itself.
+	expectedOrders := [][4]int{[4]int{1, 2, 2, 3}, [4]int{0, 0, 2, 2}, [4]int{2, 2, 2, 3}}
+	for _, expectedOrder := range expectedOrders {
+		if verifyOrder(expectedOrder) == false {
+			t.Errorf("Expected Process %d Event %d to come before Process %d Event %d, but it did not.", expectedOrder[0], expectedOrder[1], expectedOrder[2], expectedOrder[3])
+		}
+	}
+}
+
+// Tests that every process delivers broadcasts in causal order when several processes broadcast.
+func TestCausalBroadcastAllProcesses(t *testing.T) {
+	p0Events := []string{"B", "R1", "R2", "B"}
+	p1Events := []string{"R2", "R0", "B", "R0"}
+	p2Events := []string{"B", "R1", "R0", "R0"}
+	events := [][]string{p0Events, p1Events, p2Events}
+
+	if err := RunVectorClock(events); err != nil {
+		t.Fatal(err)
+	}
+
+	// Process 0's second broadcast depends on every other broadcast, so it must be delivered last everywhere.
+	for _, id := range []int{1, 2} {
+		order := deliveryOrder(id)
+		if len(order) != 3 || order[2] != 0 {
+			t.Errorf("Expected process %d to deliver process 0's second broadcast last, but got %v", id, order)
+		}
+	}
+	// Process 1's broadcast depends on process 0's first broadcast.
+	order := deliveryOrder(2)
+	if len(order) != 3 {
+		t.Fatalf("Expected process 2 to deliver 3 broadcasts, but got %v", order)
+	}
+	if order[0] != 0 || order[1] != 1 {
+		t.Errorf("Expected process 2 to deliver process 0's first broadcast before process 1's, but got %v", order)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/kulvirs/Concurrency-A2/vector-clocks/vclock"
+)
+
+// Vector clock compression with the Singhal-Kshemkalyani differential technique. Each S event sends only the
+// entries of the sender's vector clock that changed since its last S event to the same process, and the receiver
+// rebuilds the sender's whole clock from the last one it rebuilt from that sender, so every node ends up with
+// exactly the same clocks as when whole clocks are sent. Broadcasts, forks and joins still carry whole clocks,
+// since a broadcast is only merged when it is delivered, which can be after later messages from its sender.
+
+// WireStats counts what the vector clocks of the messages sent by S events cost, both sent whole and sent with
+// differential compression.
+type WireStats struct {
+	// The number of messages sent.
+	Messages int
+	// The number of entries sent when every message carries the whole vector clock.
+	FullEntries int
+	// The number of bytes sent when every message carries the whole vector clock, with each entry as a varint.
+	FullBytes int
+	// The number of entries sent with differential compression.
+	DifferentialEntries int
+	// The number of bytes sent with differential compression, with each process and entry as a varint.
+	DifferentialBytes int
+}
+
+// Records the wire stats of each process, when running with differential compression.
+var allWireStats []WireStats
+
+// WithDifferentialClocks makes every S event send only the entries of the vector clock that changed since the
+// last message to the same process, recording what was sent in allWireStats.
+func WithDifferentialClocks() Option {
+	return func(c *config) {
+		c.differential = true
+	}
+}
+
+// Replaces the whole vector clock on a message to dest with the entries that changed since the last message to
+// dest, counting the cost of both.
+func (state *nodeState) compress(dest int, msg Message) Message {
+	msg.Entries = state.differential.Entries(dest)
+	state.wireStats.Messages++
+	state.wireStats.FullEntries += len(msg.Clock.Counters)
+	state.wireStats.FullBytes += vclock.EncodedSize(msg.Clock.Counters)
+	state.wireStats.DifferentialEntries += len(msg.Entries)
+	state.wireStats.DifferentialBytes += vclock.EncodedEntriesSize(msg.Entries)
+	msg.Clock = vclock.VectorClock{ID: msg.Clock.ID}
+	return msg
+}
+
+// Rebuilds the whole vector clock of a compressed message from source. Other messages are returned as they are.
+func (state *nodeState) decompress(source int, msg Message) Message {
+	if msg.Entries == nil {
+		return msg
+	}
+	if state.differential == nil {
+		// A process running on its own can be sent compressed messages without compressing its own.
+		state.differential = vclock.NewDifferential(state.clock, numProcesses)
+	}
+	msg.Clock = *state.differential.Rebuild(source, msg.Entries)
+	msg.Entries = nil
+	return msg
+}
+
+// Returns the wire stats of every process added together.
+func totalWireStats() WireStats {
+	total := WireStats{}
+	for _, stats := range allWireStats {
+		total.Messages += stats.Messages
+		total.FullEntries += stats.FullEntries
+		total.FullBytes += stats.FullBytes
+		total.DifferentialEntries += stats.DifferentialEntries
+		total.DifferentialBytes += stats.DifferentialBytes
+	}
+	return total
+}
+
+// Prints how many entries and bytes the vector clocks on messages took whole and with differential compression.
+func printWireStats() {
+	total := totalWireStats()
+	fmt.Printf("Vector clocks on %d message(s): %d entries (%d bytes) sent whole, %d entries (%d bytes) sent with differential compression",
+		total.Messages, total.FullEntries, total.FullBytes, total.DifferentialEntries, total.DifferentialBytes)
+	if total.FullBytes > 0 {
+		fmt.Printf(", saving %.0f%% of the bytes", 100*float64(total.FullBytes-total.DifferentialBytes)/float64(total.FullBytes))
+	}
+	fmt.Printf(".\n")
+}
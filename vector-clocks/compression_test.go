@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kulvirs/Concurrency-A2/sched"
+)
+
+// Tests that every node records the same clocks with differential compression as with whole clocks.
+func TestDifferentialClocksMatch(t *testing.T) {
+	scripts := [][][]string{
+		{{"S1", "R1", "P", "R1"}, {"S0", "R0", "P", "S2", "S0", "P", "R2"}, {"P", "S1", "R1", "P"}},
+		{{"S1", "S2", "S1", "R2"}, {"R0", "S2", "R0", "S2"}, {"R0", "R1", "R1", "S0"}},
+		{{"B", "R1", "S1"}, {"R0", "B", "R0"}, {"R1", "R0"}},
+		{{"R*", "R*", "S1", "S2"}, {"S0", "R0"}, {"S0", "R0"}},
+		{{"S1", "C", "R1"}, {"R0", "S0", "P"}, {"P"}},
+		{{"F1", "S1", "J1"}, {"R0", "S2", "P"}, {"R1"}},
+	}
+	for _, events := range scripts {
+		for seed := int64(1); seed <= 5; seed++ {
+			if err := RunVectorClock(events, WithScheduler(sched.New(seed))); err != nil {
+				t.Fatalf("Expected no error for %v but got %v", events, err)
+			}
+			expected := allClockValues
+			if err := RunVectorClock(events, WithScheduler(sched.New(seed)), WithDifferentialClocks()); err != nil {
+				t.Fatalf("Expected no error for %v with compression but got %v", events, err)
+			}
+			if !reflect.DeepEqual(allClockValues, expected) {
+				t.Errorf("Expected clocks %v for %v with compression but got %v", expected, events, allClockValues)
+			}
+		}
+	}
+}
+
+// Tests that compression saves most of the entries when processes in a large system each talk to one partner.
+func TestWireStats(t *testing.T) {
+	n, rounds := 20, 5
+	events := make([][]string, n)
+	for i := 0; i < n; i += 2 {
+		for round := 0; round < rounds; round++ {
+			events[i] = append(events[i], fmt.Sprintf("S%d", i+1), fmt.Sprintf("R%d", i+1))
+			events[i+1] = append(events[i+1], fmt.Sprintf("R%d", i), fmt.Sprintf("S%d", i))
+		}
+	}
+	if err := RunVectorClock(events, WithDifferentialClocks()); err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	stats := totalWireStats()
+	if stats.Messages != n*rounds || stats.FullEntries != n*n*rounds {
+		t.Errorf("Expected %d messages of %d entries each but got %+v", n*rounds, n, stats)
+	}
+	// Only the two counters of a pair ever change, so every message but the first of each pair carries two entries.
+	if stats.DifferentialEntries != 2*n*rounds-n/2 || stats.DifferentialBytes >= stats.FullBytes/4 {
+		t.Errorf("Expected compression to send %d entries but got %+v", 2*n*rounds-n/2, stats)
+	}
+	if err := RunVectorClock(events); err != nil || totalWireStats() != (WireStats{}) {
+		t.Errorf("Expected nothing to be counted without compression but got %+v, %v", totalWireStats(), err)
+	}
+}
+
+// Tests that compressed messages sent over TCP rebuild the same clocks as whole clocks sent over channels.
+func TestDifferentialClocksTCP(t *testing.T) {
+	events := [][]string{{"S1", "R1", "S2", "R1"}, {"R0", "S0", "S2", "S0"}, {"R0", "R1"}}
+	if err := RunVectorClock(events); err != nil {
+		t.Fatal(err)
+	}
+	expected := allClockValues
+	tcp, err := NewTCPTransport(freeAddresses(t, 3), []int{0, 1, 2}, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := RunVectorClock(events, WithTransport(tcp), WithDifferentialClocks()); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(allClockValues, expected) {
+		t.Errorf("Expected the clocks %v over TCP, but got %v", expected, allClockValues)
+	}
+}
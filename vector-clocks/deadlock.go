@@ -0,0 +1,237 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/kulvirs/Concurrency-A2/sched"
+)
+
+// StuckProcess describes a process that could not make progress when a deadlock was detected.
+type StuckProcess struct {
+	// The id of the stuck process.
+	ID int
+	// The index of the event the process is stuck on.
+	Index int
+	// The event the process is stuck on.
+	Event Event
+	// The process it is waiting to send to or receive from, or that holds the lock it is waiting for. anySource
+	// if it is waiting to receive from any process.
+	Peer int
+}
+
+// DeadlockError is returned when every unfinished process is blocked on a channel that will never become ready.
+type DeadlockError struct {
+	Stuck []StuckProcess
+}
+
+// Error describes which processes are stuck, where, and who they are waiting on.
+func (err *DeadlockError) Error() string {
+	lines := []string{"deadlock detected:"}
+	for _, p := range err.Stuck {
+		if p.Event.Type == 'R' && p.Event.Any {
+			lines = append(lines, fmt.Sprintf("  process %d is stuck at event %d (%v) waiting to receive from any process", p.ID, p.Index, p.Event))
+		} else if p.Event.Type == 'R' {
+			lines = append(lines, fmt.Sprintf("  process %d is stuck at event %d (%v) waiting to receive from process %d", p.ID, p.Index, p.Event, p.Peer))
+		} else if p.Event.Type == 'M' {
+			lines = append(lines, fmt.Sprintf("  process %d is stuck after its last event waiting for a snapshot marker", p.ID))
+		} else if p.Event.Type == 'L' {
+			lines = append(lines, fmt.Sprintf("  process %d is stuck at event %d (%v) waiting for %s held by process %d", p.ID, p.Index, p.Event, lockName(p.Event.Variable), p.Peer))
+		} else if p.Event.Type == 'J' {
+			lines = append(lines, fmt.Sprintf("  process %d is stuck at event %d (%v) waiting for process %d to finish", p.ID, p.Index, p.Event, p.Peer))
+		} else {
+			lines = append(lines, fmt.Sprintf("  process %d is stuck at event %d (%v) waiting to send to process %d (channel full)", p.ID, p.Index, p.Event, p.Peer))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Monitors the channel operations of every process so that a deadlock is reported instead of hanging.
+// Channel operations are attempted without blocking while holding the mutex, so a process that fails its
+// attempt knows no other process is midway through an operation that could unblock it.
+type monitor struct {
+	mutex sync.Mutex
+	cond  *sync.Cond
+	// The events of every process, used to describe where stuck processes are.
+	events [][]Event
+	// The index of the event each process is currently running.
+	positions []int
+	// The process each process is currently sending to or receiving from.
+	peers []int
+	// Whether each process failed its last attempt and nothing has succeeded since.
+	waiting []bool
+	// Whether each process has started. Processes that are forked start when they are forked.
+	started []bool
+	// Whether each process has run all of its events.
+	finished []bool
+	// Set once a deadlock has been detected.
+	err *DeadlockError
+	// Runs the processes one step at a time when set, otherwise they run whenever the Go scheduler picks them.
+	scheduler *sched.Scheduler
+	// Called with the stuck processes once every process is waiting, before a deadlock is reported, so the
+	// transport can let them make progress, such as by timing out receives. Returns true if it did.
+	quiet func(stuck []StuckProcess) bool
+}
+
+// Stores the monitor for the current simulation.
+var deadlockMonitor *monitor
+
+// Creates a monitor for processes running the given events, where the initial processes start straight away.
+// If scheduler is not nil, every channel operation is a step picked by the scheduler.
+func newMonitor(events [][]Event, initial []int, scheduler *sched.Scheduler) *monitor {
+	m := &monitor{
+		events:    events,
+		positions: make([]int, len(events)),
+		peers:     make([]int, len(events)),
+		waiting:   make([]bool, len(events)),
+		started:   make([]bool, len(events)),
+		finished:  make([]bool, len(events)),
+		scheduler: scheduler,
+	}
+	for _, id := range initial {
+		m.started[id] = true
+		if scheduler != nil {
+			scheduler.Start(id)
+		}
+	}
+	m.cond = sync.NewCond(&m.mutex)
+	return m
+}
+
+// Marks process id as started, before it is forked.
+func (m *monitor) start(id int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.started[id] = true
+	if m.scheduler != nil {
+		m.scheduler.Start(id)
+	}
+}
+
+// Waits for the scheduler to pick process id for its first step, so that it only runs when it is picked.
+// Returns false if the scheduler stopped before picking it. Does nothing without a scheduler.
+func (m *monitor) begin(id int) bool {
+	if m.scheduler == nil {
+		return true
+	}
+	return m.scheduler.DoOn(id, nil, nil, func() bool {
+		return true
+	})
+}
+
+// Repeatedly calls attempt, a non-blocking channel operation between process id and peer at the given event index,
+// until it succeeds. Returns false if a deadlock was detected before the operation could complete.
+// objects names the channels or locks the operation uses, so a scheduler can tell which operations are independent,
+// and ready reports whether attempt would succeed without changing anything, or is nil if it always succeeds.
+func (m *monitor) do(id int, index int, peer int, objects []string, ready func() bool, attempt func() bool) bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.positions[id] = index
+	m.peers[id] = peer
+	if m.scheduler != nil {
+		// The scheduler only stops once every process is blocked, so the last process to notice reports the deadlock.
+		m.mutex.Unlock()
+		done := m.scheduler.DoOn(id, objects, ready, attempt)
+		m.mutex.Lock()
+		if !done {
+			m.waiting[id] = true
+			m.deadlocked()
+		}
+		return done
+	}
+	for m.err == nil {
+		if attempt() {
+			// Something changed, so every waiting process should try again.
+			for i := range m.waiting {
+				m.waiting[i] = false
+			}
+			m.cond.Broadcast()
+			return true
+		}
+		m.waiting[id] = true
+		if m.deadlocked() {
+			break
+		}
+		// The transport may have let the processes make progress instead, so this process should try again.
+		if m.waiting[id] {
+			m.cond.Wait()
+		}
+	}
+	return false
+}
+
+// Marks process id as having run all of its events.
+func (m *monitor) finish(id int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.finished[id] = true
+	// The process has left its final state for a process that joins it, so every waiting process should try again.
+	for i := range m.waiting {
+		m.waiting[i] = false
+	}
+	m.cond.Broadcast()
+	if m.scheduler != nil {
+		m.scheduler.Exit(id)
+	}
+}
+
+// Returns true if a deadlock has been detected, checking whether every unfinished process is waiting.
+// Must be called while holding the mutex.
+func (m *monitor) deadlocked() bool {
+	if m.err != nil {
+		return true
+	}
+	stuck := []StuckProcess{}
+	for i := range m.events {
+		if !m.started[i] || m.finished[i] {
+			continue
+		}
+		if !m.waiting[i] {
+			return false
+		}
+		stuck = append(stuck, m.stuckProcess(i))
+	}
+	if len(stuck) == 0 {
+		return false
+	}
+	// Under the scheduler, the transport was already given the chance to unblock the processes before it stopped.
+	if m.quiet != nil && m.scheduler == nil && m.quiet(stuck) {
+		for i := range m.waiting {
+			m.waiting[i] = false
+		}
+		m.cond.Broadcast()
+		return false
+	}
+	m.err = &DeadlockError{stuck}
+	m.cond.Broadcast()
+	return true
+}
+
+// Describes where process id is stuck. Must be called while holding the mutex, or from the scheduler while
+// every process is waiting for it.
+func (m *monitor) stuckProcess(id int) StuckProcess {
+	// A process waiting for snapshot markers after its last event is waiting on a marker ('M').
+	e := Event{Type: 'M', Peer: m.peers[id]}
+	if m.positions[id] < len(m.events[id]) {
+		e = m.events[id][m.positions[id]]
+	}
+	peer := m.peers[id]
+	if lock, ok := locks[e.Variable]; ok && e.Type == 'L' {
+		// A process waiting for a lock is waiting on the process holding it.
+		peer = lock.holder
+	}
+	return StuckProcess{id, m.positions[id], e, peer}
+}
+
+// Called by the scheduler once every process is blocked, before it stops the run, so the transport can let the
+// processes make progress. Every process is waiting for the scheduler, so none of them can change the monitor.
+func (m *monitor) unblock() bool {
+	stuck := []StuckProcess{}
+	for i := range m.events {
+		if m.started[i] && !m.finished[i] {
+			stuck = append(stuck, m.stuckProcess(i))
+		}
+	}
+	return m.quiet(stuck)
+}
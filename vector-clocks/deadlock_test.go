@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+)
+
+// Tests that two processes that both wait to receive before sending are reported as deadlocked instead of hanging.
+func TestDeadlockReceiveCycle(t *testing.T) {
+	p0Events := []string{"P", "R1", "S1"}
+	p1Events := []string{"R0", "S0"}
+	p2Events := []string{"P", "P"}
+	events := [][]string{p0Events, p1Events, p2Events}
+
+	err := RunVectorClock(events)
+	deadlock, ok := err.(*DeadlockError)
+	if !ok {
+		t.Fatalf("Expected a deadlock error, but got %v", err)
+	}
+	if len(deadlock.Stuck) != 2 {
+		t.Fatalf("Expected processes 0 and 1 to be stuck, but got %v", deadlock.Stuck)
+	}
+	expected := []StuckProcess{{0, 1, Event{Type: 'R', Peer: 1}, 1}, {1, 0, Event{Type: 'R', Peer: 0}, 0}}
+	for i, p := range deadlock.Stuck {
+		if p != expected[i] {
+			t.Errorf("Expected stuck process %+v, but got %+v", expected[i], p)
+		}
+	}
+	// The events before the deadlock are still recorded.
+	if len(allClockValues[0]) != 1 || len(allClockValues[2]) != 2 {
+		t.Errorf("Expected the clock values before the deadlock to be recorded, but got %v", allClockValues)
+	}
+}
+
+// Tests that a process blocked sending to a full channel is reported as deadlocked.
+func TestDeadlockFullChannel(t *testing.T) {
+	// Channels hold numProcesses messages, so the fourth send to process 1 blocks until process 1 receives,
+	// but process 1 first waits on process 2, which waits on the message process 0 sends last.
+	p0Events := []string{"S1", "S1", "S1", "S1", "S2"}
+	p1Events := []string{"R2", "R0", "R0", "R0", "R0"}
+	p2Events := []string{"R0", "S1"}
+	events := [][]string{p0Events, p1Events, p2Events}
+
+	err := RunVectorClock(events)
+	deadlock, ok := err.(*DeadlockError)
+	if !ok {
+		t.Fatalf("Expected a deadlock error, but got %v", err)
+	}
+	expected := []StuckProcess{{0, 3, Event{Type: 'S', Peer: 1}, 1}, {1, 0, Event{Type: 'R', Peer: 2}, 2}, {2, 0, Event{Type: 'R', Peer: 0}, 0}}
+	if len(deadlock.Stuck) != len(expected) {
+		t.Fatalf("Expected all processes to be stuck, but got %v", deadlock.Stuck)
+	}
+	for i, p := range deadlock.Stuck {
+		if p != expected[i] {
+			t.Errorf("Expected stuck process %+v, but got %+v", expected[i], p)
+		}
+	}
+}
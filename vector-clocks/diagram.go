@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"io"
+
+	"github.com/kulvirs/Concurrency-A2/vector-clocks/vclock"
+)
+
+// Returns the events at each process i that sent a message to each process j, in order, as sent[i][j].
+func sentMessages() [][][]EventID {
+	sent := make([][][]EventID, len(allTimelineEvents))
+	for i, timelineEvents := range allTimelineEvents {
+		sent[i] = make([][]EventID, len(allTimelineEvents))
+		for j, e := range timelineEvents {
+			switch e.Type {
+			case 'S':
+				sent[i][e.Peer] = append(sent[i][e.Peer], EventID{i, j})
+			case 'B':
+				for dest := range allTimelineEvents {
+					if dest != i {
+						sent[i][dest] = append(sent[i][dest], EventID{i, j})
+					}
+				}
+			}
+		}
+	}
+	return sent
+}
+
+// Returns every message in the recorded timelines as a pair of its send (or broadcast) event and its receive event.
+// Channels are FIFO, so the k-th message process i sends to process j is the k-th message process j receives from i,
+// unless faults were injected, in which case each receive records which message it received.
+// Messages that were never received (after a deadlock, or lost to a fault) are left out.
+func messageEdges() [][2]EventID {
+	sent := sentMessages()
+	edges := [][2]EventID{}
+	for j, timelineEvents := range allTimelineEvents {
+		received := make([]int, len(allTimelineEvents))
+		for k, e := range timelineEvents {
+			if e.Type == 'R' && !e.TimedOut {
+				if e.Sequence > 0 {
+					received[e.Peer] = e.Sequence - 1
+				}
+				edges = append(edges, [2]EventID{sent[e.Peer][j][received[e.Peer]], {j, k}})
+				received[e.Peer]++
+			}
+		}
+	}
+	return edges
+}
+
+// Writes the recorded timelines as a Graphviz DOT space-time diagram. Each process is drawn as a horizontal lane
+// of events labelled with their vector clocks, and each message as an arrow from its send to its receive.
+func writeDot(w io.Writer) error {
+	fmt.Fprintf(w, "digraph vectorclocks {\n")
+	fmt.Fprintf(w, "\trankdir=LR;\n")
+	fmt.Fprintf(w, "\tnode [shape=box, fontname=\"monospace\"];\n")
+	for i, clockValues := range allClockValues {
+		fmt.Fprintf(w, "\tsubgraph cluster_P%d {\n", i)
+		fmt.Fprintf(w, "\t\tlabel=\"P%d\";\n", i)
+		for j, clockValue := range clockValues {
+			fmt.Fprintf(w, "\t\t\"%v\" [label=\"%v\\n%s\"];\n", EventID{i, j}, EventID{i, j}, vclock.FormatCounters(clockValue))
+			if j > 0 {
+				fmt.Fprintf(w, "\t\t\"%v\" -> \"%v\" [arrowhead=none, weight=10];\n", EventID{i, j - 1}, EventID{i, j})
+			}
+		}
+		fmt.Fprintf(w, "\t}\n")
+	}
+	for _, edge := range messageEdges() {
+		fmt.Fprintf(w, "\t\"%v\" -> \"%v\" [color=blue];\n", edge[0], edge[1])
+	}
+	_, err := fmt.Fprintf(w, "}\n")
+	return err
+}
+
+// Dimensions used when drawing SVG diagrams.
+const (
+	svgMargin     = 60
+	svgLaneHeight = 80
+	svgStepWidth  = 90
+	svgRadius     = 6
+)
+
+// Returns the column of every event for drawing, so that each event is to the right of the event before it
+// in its process and to the right of the send of any message it receives.
+func eventColumns() [][]int {
+	columns := make([][]int, len(allClockValues))
+	for i, clockValues := range allClockValues {
+		columns[i] = make([]int, len(clockValues))
+		for j := range clockValues {
+			columns[i][j] = j
+		}
+	}
+	edges := messageEdges()
+	// The diagram is acyclic, so pushing events right until nothing moves terminates.
+	for changed := true; changed; {
+		changed = false
+		for _, edge := range edges {
+			from, to := edge[0], edge[1]
+			if columns[to.Process][to.Index] <= columns[from.Process][from.Index] {
+				shift := columns[from.Process][from.Index] + 1 - columns[to.Process][to.Index]
+				for k := to.Index; k < len(columns[to.Process]); k++ {
+					columns[to.Process][k] += shift
+				}
+				changed = true
+			}
+		}
+	}
+	return columns
+}
+
+// Writes the recorded timelines as a self-contained SVG space-time diagram, laid out the same way as writeDot.
+func writeSVG(w io.Writer) error {
+	columns := eventColumns()
+	maxColumn := 0
+	for _, processColumns := range columns {
+		for _, column := range processColumns {
+			if column > maxColumn {
+				maxColumn = column
+			}
+		}
+	}
+	x := func(id EventID) int { return 2*svgMargin + columns[id.Process][id.Index]*svgStepWidth }
+	y := func(id EventID) int { return svgMargin + id.Process*svgLaneHeight }
+	width := 3*svgMargin + maxColumn*svgStepWidth
+	height := svgMargin + len(allClockValues)*svgLaneHeight
+
+	fmt.Fprintf(w, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" font-family=\"monospace\" font-size=\"12\">\n", width, height)
+	fmt.Fprintf(w, "<defs><marker id=\"arrow\" viewBox=\"0 0 10 10\" refX=\"10\" refY=\"5\" markerWidth=\"8\" markerHeight=\"8\" orient=\"auto\"><path d=\"M0,0 L10,5 L0,10 z\" fill=\"blue\"/></marker></defs>\n")
+	for i := range allClockValues {
+		lane := EventID{i, 0}
+		fmt.Fprintf(w, "<text x=\"%d\" y=\"%d\" font-weight=\"bold\">P%d</text>\n", svgMargin/2, y(lane)+4, i)
+		fmt.Fprintf(w, "<line x1=\"%d\" y1=\"%d\" x2=\"%d\" y2=\"%d\" stroke=\"black\"/>\n", svgMargin+svgMargin/2, y(lane), width-svgMargin, y(lane))
+	}
+	for _, edge := range messageEdges() {
+		fmt.Fprintf(w, "<line x1=\"%d\" y1=\"%d\" x2=\"%d\" y2=\"%d\" stroke=\"blue\" marker-end=\"url(#arrow)\"/>\n", x(edge[0]), y(edge[0]), x(edge[1]), y(edge[1]))
+	}
+	for i, clockValues := range allClockValues {
+		for j, clockValue := range clockValues {
+			id := EventID{i, j}
+			fmt.Fprintf(w, "<circle cx=\"%d\" cy=\"%d\" r=\"%d\"><title>%v</title></circle>\n", x(id), y(id), svgRadius, id)
+			fmt.Fprintf(w, "<text x=\"%d\" y=\"%d\" text-anchor=\"middle\">%s</text>\n", x(id), y(id)-2*svgRadius, html.EscapeString(vclock.FormatCounters(clockValue)))
+		}
+	}
+	_, err := fmt.Fprintf(w, "</svg>\n")
+	return err
+}
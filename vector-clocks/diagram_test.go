@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+// Tests that every send and broadcast is matched with its receive.
+func TestMessageEdges(t *testing.T) {
+	p0Events := []string{"S1", "B", "R1"}
+	p1Events := []string{"R0", "R0", "S0"}
+	p2Events := []string{"P2", "R0"}
+	events := [][]string{p0Events, p1Events, p2Events}
+
+	if err := RunVectorClock(events); err != nil {
+		t.Fatal(err)
+	}
+
+	// Process 1's receipt of the broadcast is followed by its delivery, so its send is event 3.
+	expected := map[[2]EventID]bool{
+		{{0, 0}, {1, 0}}: true,
+		{{0, 1}, {1, 1}}: true,
+		{{0, 1}, {2, 1}}: true,
+		{{1, 3}, {0, 2}}: true,
+	}
+	edges := messageEdges()
+	if len(edges) != len(expected) {
+		t.Fatalf("Expected %d messages, but got %v", len(expected), edges)
+	}
+	for _, edge := range edges {
+		if !expected[edge] {
+			t.Errorf("Unexpected message %v -> %v", edge[0], edge[1])
+		}
+	}
+}
+
+// Tests that the DOT and SVG diagrams contain every event and message.
+func TestWriteDiagrams(t *testing.T) {
+	p0Events := []string{"S1", "P0"}
+	p1Events := []string{"P1", "R0"}
+	events := [][]string{p0Events, p1Events}
+
+	if err := RunVectorClock(events); err != nil {
+		t.Fatal(err)
+	}
+
+	var dot bytes.Buffer
+	if err := writeDot(&dot); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"\"P0.e0\" [label=\"P0.e0\\n[1 0]\"]", "\"P1.e1\" [label=\"P1.e1\\n[1 2]\"]", "\"P0.e0\" -> \"P1.e1\" [color=blue]"} {
+		if !strings.Contains(dot.String(), expected) {
+			t.Errorf("Expected the DOT output to contain %s, but got:\n%s", expected, dot.String())
+		}
+	}
+
+	var svg bytes.Buffer
+	if err := writeSVG(&svg); err != nil {
+		t.Fatal(err)
+	}
+	decoder := xml.NewDecoder(&svg)
+	circles := 0
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		if start, ok := token.(xml.StartElement); ok && start.Name.Local == "circle" {
+			circles++
+		}
+	}
+	if circles != 4 {
+		t.Errorf("Expected a circle for each of the 4 events in the SVG output, but got %d", circles)
+	}
+}
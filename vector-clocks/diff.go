@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/kulvirs/Concurrency-A2/vector-clocks/vclock"
+)
+
+// Differences between two recorded runs. Events are aligned by their process and index, so an event that was
+// added to a timeline also shows every later event of that timeline as changed. For every position held in both
+// runs the diff reports the events and clocks that changed, and every pair of events on different processes whose
+// happens-before relation changed, e.g. from ordered to concurrent. The clocks compared are the ones each run
+// recorded, not ones recomputed from the events, so runs made before and after a change to how clocks are updated
+// can be compared.
+
+// EventChange is an event that was added, removed or replaced by a different event.
+type EventChange struct {
+	Event EventID
+	// The event in each run, written as in the timelines, or empty if the run does not have it.
+	Old string
+	New string
+}
+
+// ClockChange is an event in both runs whose vector clock changed.
+type ClockChange struct {
+	Event EventID
+	Old   []int
+	New   []int
+}
+
+// RelationChange is a pair of events on different processes, in both runs, whose relation changed.
+type RelationChange struct {
+	A   EventID
+	B   EventID
+	Old vclock.Ordering
+	New vclock.Ordering
+}
+
+// TraceDiff lists the differences between an old and a new run.
+type TraceDiff struct {
+	Events    []EventChange
+	Clocks    []ClockChange
+	Relations []RelationChange
+}
+
+// Returns an event as it is written in the timelines, with its label.
+func eventText(e Event) string {
+	if label := eventLabel(e); label != "" {
+		return fmt.Sprintf("%v (%s)", e, label)
+	}
+	return e.String()
+}
+
+// Returns the differences between the recorded clocks of two traces, after checking that the events of both are
+// well formed.
+func diffTraces(oldTrace Trace, newTrace Trace) (TraceDiff, error) {
+	oldEvents, oldClocks, err := recordedClocks(oldTrace)
+	if err != nil {
+		return TraceDiff{}, fmt.Errorf("old run: %v", err)
+	}
+	newEvents, newClocks, err := recordedClocks(newTrace)
+	if err != nil {
+		return TraceDiff{}, fmt.Errorf("new run: %v", err)
+	}
+
+	diff := TraceDiff{[]EventChange{}, []ClockChange{}, []RelationChange{}}
+	// The events held in both runs.
+	shared := []EventID{}
+	for i := 0; i < len(oldEvents) || i < len(newEvents); i++ {
+		for j := 0; ; j++ {
+			id := EventID{i, j}
+			change := EventChange{Event: id}
+			if i < len(oldEvents) && j < len(oldEvents[i]) {
+				change.Old = eventText(oldEvents[i][j])
+			}
+			if i < len(newEvents) && j < len(newEvents[i]) {
+				change.New = eventText(newEvents[i][j])
+			}
+			if change.Old == "" && change.New == "" {
+				break
+			}
+			if change.Old != change.New {
+				diff.Events = append(diff.Events, change)
+			}
+			if change.Old != "" && change.New != "" {
+				shared = append(shared, id)
+				oldClock, newClock := oldClocks[i][j], newClocks[i][j]
+				// A process added to the run adds an entry of 0 to every clock, which is no change.
+				if vclock.CompareCounters(oldClock, newClock) != vclock.Equal {
+					diff.Clocks = append(diff.Clocks, ClockChange{id, oldClock, newClock})
+				}
+			}
+		}
+	}
+	for k, a := range shared {
+		for _, b := range shared[k+1:] {
+			if a.Process == b.Process {
+				continue
+			}
+			oldOrdering := vclock.CompareCounters(oldClocks[a.Process][a.Index], oldClocks[b.Process][b.Index])
+			newOrdering := vclock.CompareCounters(newClocks[a.Process][a.Index], newClocks[b.Process][b.Index])
+			if oldOrdering != newOrdering {
+				diff.Relations = append(diff.Relations, RelationChange{a, b, oldOrdering, newOrdering})
+			}
+		}
+	}
+	return diff, nil
+}
+
+// Returns the relation between two events, e.g. "P0.e1 -> P1.e2" or "P0.e1 || P1.e2".
+func relationText(a EventID, b EventID, ordering vclock.Ordering) string {
+	switch ordering {
+	case vclock.Before:
+		return fmt.Sprintf("%v -> %v", a, b)
+	case vclock.After:
+		return fmt.Sprintf("%v -> %v", b, a)
+	case vclock.Concurrent:
+		return fmt.Sprintf("%v || %v", a, b)
+	}
+	return fmt.Sprintf("%v == %v", a, b)
+}
+
+// Prints the events, clocks and relations that changed from the old run to the new run.
+func printTraceDiff(diff TraceDiff) {
+	if len(diff.Events) == 0 && len(diff.Clocks) == 0 {
+		fmt.Printf("The runs have the same events and clocks.\n")
+		return
+	}
+	fmt.Printf("Changed events:\n")
+	for _, change := range diff.Events {
+		if change.Old == "" {
+			fmt.Printf("  %v: added %s\n", change.Event, change.New)
+		} else if change.New == "" {
+			fmt.Printf("  %v: removed %s\n", change.Event, change.Old)
+		} else {
+			fmt.Printf("  %v: %s became %s\n", change.Event, change.Old, change.New)
+		}
+	}
+	fmt.Printf("Changed clocks:\n")
+	for _, change := range diff.Clocks {
+		fmt.Printf("  %v: %s became %s\n", change.Event, vclock.FormatCounters(change.Old), vclock.FormatCounters(change.New))
+	}
+	fmt.Printf("Changed relations:\n")
+	for _, change := range diff.Relations {
+		fmt.Printf("  %s became %s\n", relationText(change.A, change.B, change.Old), relationText(change.A, change.B, change.New))
+	}
+}
+
+// Compares the run recorded in the trace in the given file, as the old run, with the last run or loaded trace.
+func diffTraceFile(file string) (TraceDiff, error) {
+	oldTrace, err := readTraceFile(file)
+	if err != nil {
+		return TraceDiff{}, err
+	}
+	return diffTraces(oldTrace, buildTrace())
+}
+
+// Compares the runs recorded in the traces in the given files.
+func diffTraceFiles(oldFile string, newFile string) (TraceDiff, error) {
+	oldTrace, err := readTraceFile(oldFile)
+	if err != nil {
+		return TraceDiff{}, err
+	}
+	newTrace, err := readTraceFile(newFile)
+	if err != nil {
+		return TraceDiff{}, err
+	}
+	return diffTraces(oldTrace, newTrace)
+}
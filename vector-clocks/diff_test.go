@@ -0,0 +1,112 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kulvirs/Concurrency-A2/vector-clocks/vclock"
+)
+
+// Runs the events and returns the trace of the run.
+func runTrace(t *testing.T, events [][]string) Trace {
+	if err := RunVectorClock(events); err != nil {
+		t.Fatalf("Expected %v to run but got %v", events, err)
+	}
+	return buildTrace()
+}
+
+// Tests that moving a send reports the events and clocks that changed and the pairs that became ordered.
+func TestDiffTraces(t *testing.T) {
+	oldTrace := runTrace(t, [][]string{{"S1", "P"}, {"R0", "P"}})
+	newTrace := runTrace(t, [][]string{{"P", "S1"}, {"R0", "P"}})
+	diff, err := diffTraces(oldTrace, newTrace)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	events := []EventChange{{EventID{0, 0}, "S1", "P"}, {EventID{0, 1}, "P", "S1"}}
+	if !reflect.DeepEqual(diff.Events, events) {
+		t.Errorf("Expected changed events %v but got %v", events, diff.Events)
+	}
+	clocks := []ClockChange{{EventID{1, 0}, []int{1, 1}, []int{2, 1}}, {EventID{1, 1}, []int{1, 2}, []int{2, 2}}}
+	if !reflect.DeepEqual(diff.Clocks, clocks) {
+		t.Errorf("Expected changed clocks %v but got %v", clocks, diff.Clocks)
+	}
+	relations := []RelationChange{
+		{EventID{0, 1}, EventID{1, 0}, vclock.Concurrent, vclock.Before},
+		{EventID{0, 1}, EventID{1, 1}, vclock.Concurrent, vclock.Before},
+	}
+	if !reflect.DeepEqual(diff.Relations, relations) {
+		t.Errorf("Expected changed relations %v but got %v", relations, diff.Relations)
+	}
+}
+
+// Tests that sends and receives added in the new run, including those of a new process, are reported as added,
+// and the other way around as removed, without the new process's clock entry counting as a change.
+func TestDiffTracesAddedAndRemoved(t *testing.T) {
+	oldTrace := runTrace(t, [][]string{{"S1", "P"}, {"R0"}})
+	newTrace := runTrace(t, [][]string{{"S1", "P"}, {"R0", "S2"}, {"R1"}})
+	diff, err := diffTraces(oldTrace, newTrace)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	events := []EventChange{{EventID{1, 1}, "", "S2"}, {EventID{2, 0}, "", "R1"}}
+	if !reflect.DeepEqual(diff.Events, events) {
+		t.Errorf("Expected added events %v but got %v", events, diff.Events)
+	}
+	if len(diff.Clocks) != 0 || len(diff.Relations) != 0 {
+		t.Errorf("Expected no changed clocks or relations but got %v and %v", diff.Clocks, diff.Relations)
+	}
+
+	diff, err = diffTraces(newTrace, oldTrace)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	events = []EventChange{{EventID{1, 1}, "S2", ""}, {EventID{2, 0}, "R1", ""}}
+	if !reflect.DeepEqual(diff.Events, events) {
+		t.Errorf("Expected removed events %v but got %v", events, diff.Events)
+	}
+}
+
+// Tests that a run compared with itself has no differences, and that a trace whose receives are not linked to
+// sends is not compared.
+func TestDiffTracesSame(t *testing.T) {
+	trace := runTrace(t, [][]string{{`S1:"hi"`, "B", "R*"}, {"R0", "R0", "S0"}})
+	diff, err := diffTraces(trace, trace)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	if len(diff.Events) != 0 || len(diff.Clocks) != 0 || len(diff.Relations) != 0 {
+		t.Errorf("Expected no differences but got %+v", diff)
+	}
+	trace.Processes[1].Events[0].Message = 99
+	_, err = diffTraces(runTrace(t, [][]string{{"P"}}), trace)
+	if err == nil || !strings.HasPrefix(err.Error(), "new run: message 99 is received by P1.e0 but never sent") {
+		t.Errorf("Expected the new run's receive to be rejected but got %v", err)
+	}
+}
+
+// Tests that the recorded clocks are compared, so a run recorded under a different rule for merging clocks, whose
+// clocks do not recompute from its events, is still compared.
+func TestDiffTracesRecordedClocks(t *testing.T) {
+	oldTrace := runTrace(t, [][]string{{"S1", "P"}, {"R0", "P"}})
+	newTrace := runTrace(t, [][]string{{"S1", "P"}, {"R0", "P"}})
+	// The clocks of P1 if a receive also counted an event of the sender.
+	newTrace.Processes[1].Events[0].Clock = []int{2, 1}
+	newTrace.Processes[1].Events[1].Clock = []int{2, 2}
+	diff, err := diffTraces(oldTrace, newTrace)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	clocks := []ClockChange{{EventID{1, 0}, []int{1, 1}, []int{2, 1}}, {EventID{1, 1}, []int{1, 2}, []int{2, 2}}}
+	if len(diff.Events) != 0 || !reflect.DeepEqual(diff.Clocks, clocks) {
+		t.Errorf("Expected only the changed clocks %v but got %+v", clocks, diff)
+	}
+	relations := []RelationChange{
+		{EventID{0, 1}, EventID{1, 0}, vclock.Concurrent, vclock.Before},
+		{EventID{0, 1}, EventID{1, 1}, vclock.Concurrent, vclock.Before},
+	}
+	if !reflect.DeepEqual(diff.Relations, relations) {
+		t.Errorf("Expected changed relations %v but got %v", relations, diff.Relations)
+	}
+}
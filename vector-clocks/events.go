@@ -0,0 +1,364 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Event represents a single parsed event in a process's list of events.
+type Event struct {
+	// The type of event: 'P' (local), 'S' (send), 'R' (receive), 'B' (broadcast), 'W' (wait), 'F' (fork), 'J' (join),
+	// 'C' (snapshot), 'r' (read of a shared variable), 'w' (write of a shared variable), 'L' (lock) or 'U' (unlock). Timelines can also contain 'D' (delivery of a broadcast) events, but never contain waits or snapshots.
+	Type byte
+	// The process that a message is sent to or received from, or that is forked or joined. For local events,
+	// broadcasts, snapshots and waits, the process itself. For receives from
+	// any process, anySource, and in timelines the process the message was received from, or still anySource if
+	// the receive timed out.
+	Peer int
+	// Set for receives from any process (R*).
+	Any bool
+	// For waits, the number of ticks to wait.
+	Ticks int
+	// Set in timelines for receives and deliveries of a broadcast message.
+	Broadcast bool
+	// For local events, the local variables the event assigns, in the form "x=3 y=1".
+	Assign string
+	// For reads and writes, the shared variable accessed. For locks and unlocks, the name of the lock, which is
+	// empty for the default lock.
+	Variable string
+	// The label written after the event, e.g. "order#42" for S1:"order#42". A send or broadcast carries its label
+	// to the receiver as the payload of the message.
+	Label string
+	// Set in timelines for receives and deliveries, the payload of the message received.
+	Payload string
+	// Set in timelines for receives that timed out, when injecting faults.
+	TimedOut bool
+	// Set in timelines for receives when injecting faults, the position of the message received among those sent
+	// over its link, counting from 1.
+	Sequence int
+}
+
+// EventError is an invalid command in the commands of a process.
+type EventError struct {
+	// The process whose commands contain the invalid command.
+	Process int
+	// The index of the invalid command in the process's commands.
+	Command int
+	// The index of the event the command is part of.
+	Event int
+	Err   error
+}
+
+func (err *EventError) Error() string {
+	return fmt.Sprintf("process %d event %d: %v", err.Process, err.Event, err.Err)
+}
+
+// String returns the event in the same form as it is written in the input.
+func (e Event) String() string {
+	command := fmt.Sprintf("%c%d", e.Type, e.Peer)
+	if e.Type == 'P' || e.Type == 'B' || e.Type == 'C' {
+		command = string(e.Type)
+	} else if e.Type == 'r' || e.Type == 'w' {
+		command = strings.ToUpper(string(e.Type)) + e.Variable
+	} else if e.Type == 'L' || e.Type == 'U' {
+		command = string(e.Type) + e.Variable
+	} else if e.Type == 'W' {
+		command = fmt.Sprintf("W%d", e.Ticks)
+	} else if e.Any {
+		command = "R*"
+	}
+	if e.Label != "" {
+		command += ":" + strconv.Quote(e.Label)
+	}
+	if e.Type == 'P' && e.Assign != "" {
+		command += " " + e.Assign
+	}
+	return command
+}
+
+// Splits a label of the form :"text" off the end of a command, returning the command and the unquoted label.
+// The label is empty if the command does not have one.
+func splitLabel(command string) (string, string, error) {
+	i := strings.Index(command, ":\"")
+	if i < 0 {
+		return command, "", nil
+	}
+	label, err := strconv.Unquote(command[i+1:])
+	if err != nil {
+		return "", "", errors.New("invalid label: " + command[i+1:])
+	}
+	return command[:i], label, nil
+}
+
+// Parses a single command for process id of the given number of processes, which can end in a label such as
+// :"order#42".
+func parseEvent(id int, processes int, command string) (Event, error) {
+	command, label, err := splitLabel(command)
+	if err != nil {
+		return Event{}, err
+	}
+	e, err := parseCommand(id, processes, command)
+	e.Label = label
+	return e, err
+}
+
+// Parses a single command for process id of the given number of processes, without a label.
+func parseCommand(id int, processes int, command string) (Event, error) {
+	if command == "" {
+		return Event{}, errors.New("empty command")
+	}
+	// Writes and reads of a shared variable are told apart from waits and receives by the letter after the W or R.
+	if command[0] == 'W' && validVariable(command[1:]) {
+		return Event{Type: 'w', Peer: id, Variable: command[1:]}, nil
+	}
+	if command[0] == 'R' && validVariable(command[1:]) {
+		return Event{Type: 'r', Peer: id, Variable: command[1:]}, nil
+	}
+	if command == "R*" {
+		// receive from whichever process's message arrives first
+		return Event{Type: 'R', Peer: anySource, Any: true}, nil
+	}
+	switch command[0] {
+	case 'P':
+		// local process, anything after the P is ignored
+		return Event{Type: 'P', Peer: id}, nil
+	case 'B':
+		// broadcast to every other process
+		if command != "B" {
+			return Event{}, errors.New("invalid command: " + command)
+		}
+		return Event{Type: 'B', Peer: id}, nil
+	case 'C':
+		// initiate a snapshot
+		if command != "C" {
+			return Event{}, errors.New("invalid command: " + command)
+		}
+		return Event{Type: 'C', Peer: id}, nil
+	case 'L', 'U':
+		// lock or unlock, either the default lock or a named one
+		if command != command[:1] && !validVariable(command[1:]) {
+			return Event{}, errors.New("invalid command: " + command)
+		}
+		return Event{Type: command[0], Peer: id, Variable: command[1:]}, nil
+	case 'W':
+		// wait for a number of ticks of simulated time
+		ticks, err := strconv.Atoi(command[1:])
+		if err != nil || ticks <= 0 {
+			return Event{}, errors.New("invalid command: " + command)
+		}
+		return Event{Type: 'W', Peer: id, Ticks: ticks}, nil
+	case 'S', 'R', 'F', 'J':
+		peer, err := strconv.Atoi(command[1:])
+		if err != nil || peer >= processes || peer < 0 || peer == id {
+			// The value after the first character should be an integer in the range [0, processes) and not equal to the current process id.
+			return Event{}, errors.New("invalid command: " + command)
+		}
+		return Event{Type: command[0], Peer: peer}, nil
+	}
+	// invalid character, should only be P, S, R, R*, B, W, F, J, C, L or U
+	return Event{}, errors.New("invalid command: " + command)
+}
+
+// Parses an assignment to a local variable of the form name=value, where value is an integer.
+func parseAssignment(command string) (string, int, error) {
+	fields := strings.SplitN(command, "=", 2)
+	value, err := strconv.Atoi(fields[1])
+	if !validVariable(fields[0]) || err != nil {
+		return "", 0, errors.New("invalid assignment: " + command)
+	}
+	return fields[0], value, nil
+}
+
+// Returns true if name is a valid local variable name: a letter followed by letters, digits or underscores.
+func validVariable(name string) bool {
+	for i, c := range name {
+		letter := c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+		if !letter && (i == 0 || c < '0' || c > '9') {
+			return false
+		}
+	}
+	return name != ""
+}
+
+// Parses the commands for every process, returning an EventError for the first invalid command. Every peer must be
+// one of the processes given commands.
+// Assignments such as x=3 are attached to the local event before them, so "P x=3 y=1" is a single event.
+func parseEvents(commands [][]string) ([][]Event, error) {
+	events := make([][]Event, len(commands))
+	for i, processCommands := range commands {
+		events[i] = []Event{}
+		for c, command := range processCommands {
+			j := len(events[i])
+			if strings.Contains(command, "=") && !strings.Contains(command, ":\"") {
+				if j == 0 || events[i][j-1].Type != 'P' {
+					return nil, &EventError{i, c, j, fmt.Errorf("assignment %s must follow a local event (P)", command)}
+				}
+				if _, _, err := parseAssignment(command); err != nil {
+					return nil, &EventError{i, c, j - 1, err}
+				}
+				events[i][j-1].Assign = strings.TrimSpace(events[i][j-1].Assign + " " + command)
+				continue
+			}
+			e, err := parseEvent(i, len(commands), command)
+			if err != nil {
+				return nil, &EventError{i, c, j, err}
+			}
+			events[i] = append(events[i], e)
+		}
+	}
+	return events, nil
+}
+
+// Checks that every send has a matching receive and every receive has a matching send. The messages to a process
+// that are not received from their sender by name must be received by its receives from any process (R*).
+// All mismatched pairs of processes are reported together.
+func validateEvents(events [][]Event) error {
+	// sends[i][j] and recvs[i][j] count the messages from process i to process j.
+	sends := make([][]int, len(events))
+	recvs := make([][]int, len(events))
+	// anyRecvs[j] counts the receives from any process (R*) at process j.
+	anyRecvs := make([]int, len(events))
+	for i := range events {
+		sends[i] = make([]int, len(events))
+		recvs[i] = make([]int, len(events))
+	}
+	for i, processEvents := range events {
+		for _, e := range processEvents {
+			switch e.Type {
+			case 'S':
+				sends[i][e.Peer]++
+			case 'B':
+				// A broadcast sends a message to every other process.
+				for j := range events {
+					if j != i {
+						sends[i][j]++
+					}
+				}
+			case 'R':
+				if e.Any {
+					anyRecvs[i]++
+				} else {
+					recvs[e.Peer][i]++
+				}
+			}
+		}
+	}
+
+	problems := []string{}
+	for j := range events {
+		// The messages to process j that no receive from a named process takes are left for its receives from any process.
+		spare := 0
+		for i := range events {
+			if sends[i][j] < recvs[i][j] {
+				problems = append(problems, fmt.Sprintf("process %d receives %d message(s) from process %d, but process %d only sends %d (unmatched R%d)", j, recvs[i][j], i, i, sends[i][j], i))
+			} else if sends[i][j] > recvs[i][j] && anyRecvs[j] == 0 {
+				problems = append(problems, fmt.Sprintf("process %d sends %d message(s) to process %d, but process %d only receives %d (unmatched S%d or B)", i, sends[i][j], j, j, recvs[i][j], j))
+			} else {
+				spare += sends[i][j] - recvs[i][j]
+			}
+		}
+		if anyRecvs[j] > 0 && spare != anyRecvs[j] {
+			problems = append(problems, fmt.Sprintf("process %d receives %d message(s) from any process, but %d message(s) sent to it are not received from a named process (unmatched R* or S%d)", j, anyRecvs[j], spare, j))
+		}
+	}
+	if len(problems) > 0 {
+		return errors.New("unmatched messages:\n  " + strings.Join(problems, "\n  "))
+	}
+	if err := validateForks(events); err != nil {
+		return err
+	}
+	if err := validateSnapshots(events); err != nil {
+		return err
+	}
+	return validateLocks(events)
+}
+
+// Checks that every process is forked and joined at most once, and that every forked process is eventually
+// started by a chain of forks from a process that starts straight away.
+func validateForks(events [][]Event) error {
+	forked := make([]bool, len(events))
+	joined := make([]bool, len(events))
+	for _, processEvents := range events {
+		for _, e := range processEvents {
+			if e.Type == 'F' {
+				if forked[e.Peer] {
+					return fmt.Errorf("process %d is forked more than once", e.Peer)
+				}
+				forked[e.Peer] = true
+			} else if e.Type == 'J' {
+				if joined[e.Peer] {
+					return fmt.Errorf("process %d is joined more than once", e.Peer)
+				}
+				joined[e.Peer] = true
+			}
+		}
+	}
+
+	parents := forkParents(events)
+	for i := range events {
+		// Follow the chain of parents, which must end at a process that is not forked.
+		seen := map[int]bool{}
+		for p := i; parents[p] >= 0; p = parents[p] {
+			if seen[p] {
+				return fmt.Errorf("process %d is never started, its forks form a cycle", i)
+			}
+			seen[p] = true
+		}
+	}
+	return nil
+}
+
+// Checks that snapshots are not mixed with fork and join, since the marker algorithm needs every process to be
+// running for the whole snapshot.
+func validateSnapshots(events [][]Event) error {
+	snapshot, forks := false, false
+	for _, processEvents := range events {
+		for _, e := range processEvents {
+			snapshot = snapshot || e.Type == 'C'
+			forks = forks || e.Type == 'F' || e.Type == 'J'
+		}
+	}
+	if snapshot && forks {
+		return errors.New("snapshots (C) cannot be taken in a run with forks (F) or joins (J)")
+	}
+	return nil
+}
+
+// Checks that every process only unlocks locks it holds, never locks a lock it already holds, and unlocks every
+// lock it locks.
+func validateLocks(events [][]Event) error {
+	for i, processEvents := range events {
+		held := map[string]bool{}
+		for j, e := range processEvents {
+			if e.Type == 'L' && held[e.Variable] {
+				return fmt.Errorf("process %d event %d: %s is already held by process %d", i, j, lockName(e.Variable), i)
+			} else if e.Type == 'U' && !held[e.Variable] {
+				return fmt.Errorf("process %d event %d: %s is not held by process %d", i, j, lockName(e.Variable), i)
+			}
+			if e.Type == 'L' || e.Type == 'U' {
+				held[e.Variable] = e.Type == 'L'
+			}
+		}
+		for name, locked := range held {
+			if locked {
+				return fmt.Errorf("process %d never releases %s", i, lockName(name))
+			}
+		}
+	}
+	return nil
+}
+
+// Checks the events of processes that run alongside processes elsewhere, whose events are not known. Each process
+// can only use events that do not need memory shared with the other processes.
+func validateLocal(events [][]Event) error {
+	for i, processEvents := range events {
+		for j, e := range processEvents {
+			if strings.ContainsRune("CFJLUrw", rune(e.Type)) {
+				return fmt.Errorf("process %d event %d: %v cannot be used by processes that run separately", i, j, e)
+			}
+		}
+	}
+	return nil
+}
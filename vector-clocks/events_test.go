@@ -0,0 +1,45 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// Tests that invalid commands are rejected with the process and event index they appear at.
+func TestParseEventsInvalid(t *testing.T) {
+	for _, command := range []string{"", "X", "S", "S5", "S1", "R-1", "R1a", "Lm-", "S0:\"open"} {
+		_, err := parseEvents([][]string{{"P"}, {"P", command}})
+		if err == nil {
+			t.Errorf("Expected command %q to be rejected, but it was not.", command)
+		} else if !strings.HasPrefix(err.Error(), "process 1 event 1:") {
+			t.Errorf("Expected the error for %q to name process 1 event 1, but got %q", command, err)
+		}
+	}
+}
+
+// Tests that a wait keeps its number of ticks apart from its peer, which is the process itself.
+func TestParseWait(t *testing.T) {
+	events, err := parseEvents([][]string{{"P"}, {"W5"}})
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	if e := events[1][0]; e.Type != 'W' || e.Ticks != 5 || e.Peer != 1 || e.String() != "W5" {
+		t.Errorf("Expected a wait of 5 ticks by process 1, but got %v with %+v", e, e)
+	}
+}
+
+// Tests that the validator reports sends and receives that have no match.
+func TestValidateEventsUnmatched(t *testing.T) {
+	err := RunVectorClock([][]string{{"R1", "R2"}, {"P"}, {"S0", "S1"}})
+	if err == nil {
+		t.Fatal("Expected unmatched messages to be reported, but they were not.")
+	}
+	for _, expected := range []string{"unmatched R1", "unmatched S1"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected the error to contain %q, but got %q", expected, err)
+		}
+	}
+	if strings.Contains(err.Error(), "unmatched S0") {
+		t.Errorf("Expected the send from process 2 to process 0 to be matched, but got %q", err)
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kulvirs/Concurrency-A2/sched"
+	"github.com/kulvirs/Concurrency-A2/vector-clocks/vclock"
+)
+
+// Exploring every run of a script. The processes run one step at a time under the scheduler, which tries every
+// order of the steps that could change the outcome, skipping orders that only swap steps on different channels
+// or locks. Properties are checked at every event of every run, and a run that breaks one or deadlocks is
+// reported with its schedule, which -schedule replays.
+
+// Property is a condition that should hold at every event of every run of a script.
+type Property struct {
+	// The name the property is reported by.
+	Name string
+	// Returns an error if the event breaks the property, reading the clocks and events recorded by the run.
+	Check func(id EventID) error
+}
+
+// The properties that can be checked by name.
+var properties = map[string]Property{
+	"monotonic": {"monotonic", checkMonotonic},
+	"bounded":   {"bounded", checkBounded},
+}
+
+// Checks that a process's vector clock never goes backwards, and that every event advances its own entry.
+func checkMonotonic(id EventID) error {
+	clock := allClockValues[id.Process][id.Index]
+	previous := make([]int, len(clock))
+	if id.Index > 0 {
+		previous = allClockValues[id.Process][id.Index-1]
+	}
+	for i := range clock {
+		if clock[i] < previous[i] {
+			return fmt.Errorf("%v has clock %s, which is behind the clock %s before it", id, vclock.FormatCounters(clock), vclock.FormatCounters(previous))
+		}
+	}
+	if clock[id.Process] == previous[id.Process] {
+		return fmt.Errorf("%v has clock %s, which does not advance its own entry", id, vclock.FormatCounters(clock))
+	}
+	return nil
+}
+
+// Checks that an event never knows of more events of a process than the process has recorded.
+func checkBounded(id EventID) error {
+	clock := allClockValues[id.Process][id.Index]
+	for i, count := range clock {
+		if count > len(allClockValues[i]) {
+			return fmt.Errorf("%v has clock %s, which knows of %d event(s) of P%d, but it only recorded %d", id, vclock.FormatCounters(clock), count, i, len(allClockValues[i]))
+		}
+	}
+	return nil
+}
+
+// Returns a property that holds at an event if the predicate holds in the global state the event knows of: the
+// state after every event in its causal past.
+func predicateProperty(p *Predicate) Property {
+	return Property{p.Text, func(id EventID) error {
+		cut := make([]int, len(allClockValues))
+		copy(cut, allClockValues[id.Process][id.Index])
+		if !p.Holds(localVariables(), cut) {
+			return fmt.Errorf("%v knows of the global state %s, in which %s does not hold", id, formatCut(cut), p.Text)
+		}
+		return nil
+	}}
+}
+
+// Returns the properties with the given names, or every property if no names are given.
+func lookupProperties(names []string) ([]Property, error) {
+	if len(names) == 0 {
+		for name := range properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+	found := []Property{}
+	for _, name := range names {
+		property, ok := properties[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown property: %s", name)
+		}
+		found = append(found, property)
+	}
+	return found, nil
+}
+
+// Violation is the first run found to break a property, or to deadlock.
+type Violation struct {
+	// The name of the property, or "deadlock".
+	Property string
+	// What went wrong in the run.
+	Message string
+	// The schedule of the run.
+	Schedule []int
+	// The number of runs that broke the property.
+	Runs int
+}
+
+// Exploration is the result of exploring every run of a script.
+type Exploration struct {
+	sched.Exploration
+	// The number of distinct sets of timelines the runs produced.
+	Outcomes int
+	// The first run found to break each property, in the order they were found.
+	Violations []*Violation
+}
+
+// Records that a run broke a property, keeping the first run found to break it.
+func (exploration *Exploration) violate(property string, message string, schedule []int) {
+	for _, v := range exploration.Violations {
+		if v.Property == property {
+			v.Runs++
+			return
+		}
+	}
+	exploration.Violations = append(exploration.Violations, &Violation{property, message, schedule, 1})
+}
+
+// Explore runs the script under every order of its steps that could change its outcome, checking the properties at
+// every event of every run, and recording a violation for every run that deadlocks. Exploring stops after limit
+// runs if limit is positive. Returns an error if the script cannot be run.
+func Explore(commands [][]string, checks []Property, limit int, options ...Option) (Exploration, error) {
+	exploration := Exploration{}
+	outcomes := map[string]bool{}
+	var err error
+	runs := 0
+	exploration.Exploration = sched.Explore(func(s *sched.Scheduler) bool {
+		runs++
+		runErr := RunVectorClock(commands, append(options, WithScheduler(s))...)
+		if s.Err() != nil {
+			// The run could not follow its schedule, so it is abandoned.
+			return limit <= 0 || runs < limit
+		}
+		if deadlock, ok := runErr.(*DeadlockError); ok {
+			exploration.violate("deadlock", deadlock.Error(), s.Schedule())
+		} else if runErr != nil {
+			err = runErr
+			return false
+		}
+		outcomes[fmt.Sprint(allClockValues)] = true
+		for _, property := range checks {
+			if problem := checkEvents(property); problem != nil {
+				exploration.violate(property.Name, problem.Error(), s.Schedule())
+			}
+		}
+		return limit <= 0 || runs < limit
+	})
+	exploration.Outcomes = len(outcomes)
+	return exploration, err
+}
+
+// Checks a property at every event of the run, returning the first problem found.
+func checkEvents(property Property) error {
+	for i, clocks := range allClockValues {
+		for j := range clocks {
+			if err := property.Check(EventID{i, j}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Prints how many runs were explored and, for each property checked, whether any run broke it, along with the
+// schedule of the first run that did.
+func printExploration(exploration Exploration, checks []Property) {
+	fmt.Printf("Explored %d run(s), %d of them abandoned at a step that could not be taken, with %d distinct outcome(s).\n",
+		exploration.Runs, exploration.Infeasible, exploration.Outcomes)
+	if !exploration.Complete {
+		fmt.Printf("Stopped at the limit before every order of the steps was tried.\n")
+	}
+	names := []string{"deadlock"}
+	for _, property := range checks {
+		names = append(names, property.Name)
+	}
+	for _, name := range names {
+		var violation *Violation
+		for _, v := range exploration.Violations {
+			if v.Property == name {
+				violation = v
+			}
+		}
+		if violation == nil {
+			if name == "deadlock" {
+				fmt.Printf("No run deadlocks.\n")
+			} else {
+				fmt.Printf("Property %s holds in every run.\n", name)
+			}
+			continue
+		}
+		if name == "deadlock" {
+			fmt.Printf("%d run(s) deadlock, first:\n", violation.Runs)
+		} else {
+			fmt.Printf("Property %s is broken by %d run(s), first:\n", name, violation.Runs)
+		}
+		fmt.Printf("  %s\n", strings.ReplaceAll(violation.Message, "\n", "\n  "))
+		fmt.Printf("  %s\n", sched.FormatSchedule(violation.Schedule))
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kulvirs/Concurrency-A2/sched"
+)
+
+// Tests that a script with only sends and receives from named processes has a single outcome, found in one run,
+// since every receive gets the same message whatever order the steps are taken in.
+func TestExploreSendsAndReceives(t *testing.T) {
+	events := [][]string{{"S2", "R1", "S1"}, {"S2", "S0", "R0"}, {"R0", "R1", "P2"}}
+	checks, _ := lookupProperties(nil)
+	exploration, err := Explore(events, checks, 0)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	if !exploration.Complete || exploration.Runs != 1 || exploration.Outcomes != 1 || len(exploration.Violations) != 0 {
+		t.Errorf("Expected a single run with no violations but got %+v", exploration)
+	}
+}
+
+// Tests that a lock ordering deadlock is found, and that its schedule replays the deadlock.
+func TestExploreDeadlock(t *testing.T) {
+	events := [][]string{{"La", "Lb", "Ub", "Ua"}, {"Lb", "La", "Ua", "Ub"}}
+	exploration, err := Explore(events, nil, 0)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	if !exploration.Complete || exploration.Outcomes != 3 || len(exploration.Violations) != 1 || exploration.Violations[0].Property != "deadlock" {
+		t.Fatalf("Expected 3 outcomes and a deadlock but got %+v", exploration)
+	}
+	err = RunVectorClock(events, WithScheduler(sched.NewReplay(exploration.Violations[0].Schedule)))
+	if _, ok := err.(*DeadlockError); !ok {
+		t.Errorf("Expected the schedule to replay a deadlock but got %v", err)
+	}
+}
+
+// Tests that a user-specified property is checked in every run, and that the schedule of the run that breaks it
+// replays the same clocks.
+func TestExploreProperty(t *testing.T) {
+	events := [][]string{{"L", "Wx", "U"}, {"L", "Wx", "U"}}
+	// Process 0 should always take the lock first, which only holds in some runs.
+	first := Property{"first", func(id EventID) error {
+		if id.Process == 1 && id.Index == 0 && allClockValues[1][0][0] == 0 {
+			return errors.New("process 1 took the lock first")
+		}
+		return nil
+	}}
+	exploration, err := Explore(events, []Property{first}, 0)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	if exploration.Outcomes != 2 || len(exploration.Violations) != 1 || exploration.Violations[0].Runs != 1 {
+		t.Fatalf("Expected 2 outcomes and one run breaking the property but got %+v", exploration)
+	}
+	if err := RunVectorClock(events, WithScheduler(sched.NewReplay(exploration.Violations[0].Schedule))); err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	if err := first.Check(EventID{1, 0}); err == nil {
+		t.Errorf("Expected the schedule to replay the run breaking the property, but got clocks %v", allClockValues)
+	}
+}
+
+// Tests that a predicate is checked in the global state each event knows of, and is only broken by the runs in
+// which process 2 hears from process 1 first.
+func TestExplorePredicate(t *testing.T) {
+	events := [][]string{{"P", "x=1", "S2"}, {"P", "y=1", "S2"}, {"R*", "P", "a=1", "R*"}}
+	predicate, err := parsePredicate("P2.a == 0 || P0.x == 1", len(events), nil)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	exploration, err := Explore(events, []Property{predicateProperty(predicate)}, 0)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	if len(exploration.Violations) != 1 || exploration.Violations[0].Property != predicate.Text {
+		t.Fatalf("Expected the predicate to be broken but got %+v", exploration.Violations)
+	}
+	expected := "P2.e1 knows of the global state (0,2,2)"
+	if message := exploration.Violations[0].Message; !strings.HasPrefix(message, expected) {
+		t.Errorf("Expected %q but got %q", expected, message)
+	}
+	if exploration.Violations[0].Runs == exploration.Runs-exploration.Infeasible {
+		t.Errorf("Expected the predicate to hold in the runs in which process 0 is heard from first")
+	}
+}
+
+// Tests that the built-in properties hold in every run of a script with snapshots, forks and locks.
+func TestExploreProperties(t *testing.T) {
+	scripts := [][][]string{
+		{{"S1", "C", "R1"}, {"R0", "S0", "P"}, {"P"}},
+		{{"F1", "S1", "J1"}, {"R0", "Wx"}},
+		{{"L", "Rx", "U", "S2"}, {"L", "Wx", "U"}, {"R0", "Rx"}},
+	}
+	checks, _ := lookupProperties(nil)
+	for _, events := range scripts {
+		exploration, err := Explore(events, checks, 0)
+		if err != nil {
+			t.Fatalf("Expected no error for %v but got %v", events, err)
+		}
+		if !exploration.Complete || len(exploration.Violations) != 0 {
+			t.Errorf("Expected no violations for %v but got %+v", events, exploration.Violations)
+		}
+	}
+}
+
+// Tests that exploring stops at the limit.
+func TestExploreLimit(t *testing.T) {
+	events := [][]string{{"L", "U"}, {"L", "U"}, {"L", "U"}}
+	exploration, err := Explore(events, nil, 2)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	if exploration.Complete || exploration.Runs != 2 {
+		t.Errorf("Expected to stop after 2 runs but got %+v", exploration)
+	}
+}
+
+// Tests that a script that cannot be run is reported, and that properties are looked up by name.
+func TestExploreErrors(t *testing.T) {
+	if _, err := Explore([][]string{{"S1"}, {"P"}}, nil, 0); err == nil {
+		t.Errorf("Expected an unmatched send to be reported")
+	}
+	if _, err := lookupProperties([]string{"monotonic", "nope"}); err == nil {
+		t.Errorf("Expected an unknown property to be reported")
+	}
+	checks, err := lookupProperties([]string{"monotonic"})
+	if err != nil || len(checks) != 1 || checks[0].Name != "monotonic" {
+		t.Errorf("Expected the monotonic property but got %v, %v", checks, err)
+	}
+	if all, _ := lookupProperties(nil); !reflect.DeepEqual([]string{all[0].Name, all[1].Name}, []string{"bounded", "monotonic"}) {
+		t.Errorf("Expected every property but got %v", all)
+	}
+}
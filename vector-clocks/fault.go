@@ -0,0 +1,549 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+
+	"github.com/kulvirs/Concurrency-A2/sched"
+)
+
+// Fault injection. The fault transport replaces the reliable FIFO channels with links that can drop, duplicate,
+// delay and reorder the messages sent by S events, each with a probability that can be set for every link.
+// Broadcasts and snapshot markers are still delivered reliably. Each link draws its faults from its own random
+// number generator, seeded from the run's seed and the link, so the same seed injects the same faults.
+//
+// A delayed message arrives a number of ticks of simulated time after it was sent, and is overtaken by the
+// messages sent on its link before then. A reordered message is overtaken by the next message sent on its link.
+// Once every process is waiting, messages still held back are delivered, and if that is not enough, every
+// waiting receive times out, so a lost message makes the receive fail instead of deadlocking the run. Under the
+// scheduler, this happens once the scheduler finds every process blocked, so a run with faults can be replayed.
+
+// LinkFaults are the probabilities of each fault happening to a message sent over a link.
+type LinkFaults struct {
+	Drop      float64
+	Duplicate float64
+	Delay     float64
+	Reorder   float64
+}
+
+// Faults configures the faults injected into the messages of a run.
+type Faults struct {
+	// The faults of every link without faults of its own.
+	All LinkFaults
+	// The faults of each link, by sender and receiver.
+	Links map[[2]int]LinkFaults
+	// Seeds the faults of every link.
+	Seed int64
+	// The most ticks a delayed message is delayed by.
+	MaxDelay int64
+	// The ticks a receive is taken to have waited when it times out, which only happens once no process can make
+	// progress and no message can arrive. A receive never times out if this is 0, and a lost message deadlocks
+	// the run instead.
+	Timeout int64
+}
+
+// Returns the faults of the link from one process to another.
+func (faults Faults) link(from int, to int) LinkFaults {
+	if link, ok := faults.Links[[2]int{from, to}]; ok {
+		return link
+	}
+	return faults.All
+}
+
+// InjectedFault is a fault that happened to a message.
+type InjectedFault struct {
+	// "dropped", "duplicated", "delayed" or "reordered".
+	Kind string
+	// The link the message was sent over.
+	From int
+	To   int
+	// The position of the message among those sent over the link, counting from 1.
+	Sequence int
+	// The ticks the message was delayed by, for a delayed message.
+	Delay int64
+}
+
+// Records the faults injected into the messages of the last run, in the order they happened on each link.
+var allInjectedFaults []InjectedFault
+
+// WithFaults sends messages over links that inject the given faults, instead of reliable channels.
+func WithFaults(faults Faults) Option {
+	return func(c *config) {
+		c.faults = &faults
+	}
+}
+
+// A message held back on its link.
+type heldMessage struct {
+	msg Message
+	// Set for a reordered message, which is released right after the next message on the link. A delayed
+	// message is released before the first message sent on the link after it arrives.
+	reordered bool
+	// The reordered messages this message overtook, which are released right after it.
+	followers []Message
+}
+
+// Returns the held message followed by the messages it overtook.
+func (h heldMessage) messages() []Message {
+	return append([]Message{h.msg}, h.followers...)
+}
+
+// The messages on their way from one process to another.
+type faultLink struct {
+	// The messages that can be received, in the order they will be received.
+	queue []Message
+	// The messages held back by a delay or a reorder.
+	held []heldMessage
+	// The number of messages sent over the link, not counting snapshot markers.
+	sent int
+	rng  *rand.Rand
+}
+
+// Carries messages over links that inject faults. Like the channel transport, every operation goes through the
+// deadlock monitor, whose mutex guards the links. Sends never wait, since the links have no limit.
+type faultTransport struct {
+	faults Faults
+	links  [][]*faultLink
+	// Whether the receive each process is waiting on has timed out.
+	expired  []bool
+	injected []InjectedFault
+}
+
+// Creates a fault transport between numProcesses processes.
+func newFaultTransport(faults Faults) *faultTransport {
+	t := &faultTransport{faults: faults, links: make([][]*faultLink, numProcesses), expired: make([]bool, numProcesses)}
+	for i := range t.links {
+		t.links[i] = make([]*faultLink, numProcesses)
+		for j := range t.links[i] {
+			seed := faults.Seed*int64(numProcesses*numProcesses) + int64(i*numProcesses+j)
+			t.links[i][j] = &faultLink{queue: []Message{}, held: []heldMessage{}, rng: rand.New(rand.NewSource(seed))}
+		}
+	}
+	return t
+}
+
+// Puts a message on the link from id to dest, injecting the link's faults.
+// Must be called while holding the deadlock monitor's mutex.
+func (t *faultTransport) put(id int, dest int, msg Message) {
+	link := t.links[id][dest]
+	if msg.Marker {
+		link.queue = append(link.queue, msg)
+		return
+	}
+	link.sent++
+	msg.Sequence = link.sent
+	// The faults are always drawn in the same order, so each message gets the same faults for the same seed.
+	faults := t.faults.link(id, dest)
+	drop := link.rng.Float64() < faults.Drop
+	duplicate := link.rng.Float64() < faults.Duplicate
+	delay := link.rng.Float64() < faults.Delay
+	reorder := link.rng.Float64() < faults.Reorder
+	delayBy := int64(0)
+	if delay {
+		delayBy = 1 + link.rng.Int63n(t.faults.MaxDelay)
+	}
+	if msg.Broadcast != nil {
+		drop, duplicate, delay, reorder = false, false, false, false
+	}
+
+	// Release the held messages this message does not overtake.
+	held, after := []heldMessage{}, []Message{}
+	for _, h := range link.held {
+		if h.reordered {
+			after = append(after, h.messages()...)
+		} else if h.msg.SentAt+h.msg.Delay <= msg.SentAt {
+			link.queue = append(link.queue, h.messages()...)
+		} else {
+			held = append(held, h)
+		}
+	}
+	copies := []Message{msg}
+	if drop {
+		t.record("dropped", id, dest, msg.Sequence, 0)
+		copies = nil
+	} else if duplicate {
+		t.record("duplicated", id, dest, msg.Sequence, 0)
+		copies = append(copies, msg)
+	}
+	for i, c := range copies {
+		if delay {
+			c.Delay = delayBy
+		}
+		if !delay && !reorder {
+			link.queue = append(link.queue, c)
+		} else if i < len(copies)-1 {
+			held = append(held, heldMessage{c, !delay, nil})
+		} else {
+			// The reordered messages this message overtakes wait for it, even if it is held back itself.
+			held = append(held, heldMessage{c, !delay, after})
+			after = nil
+		}
+	}
+	if delay && !drop {
+		t.record("delayed", id, dest, msg.Sequence, delayBy)
+	} else if reorder && !drop {
+		t.record("reordered", id, dest, msg.Sequence, 0)
+	}
+	link.queue = append(link.queue, after...)
+	link.held = held
+}
+
+// Records a fault injected into a message on the link from one process to another.
+func (t *faultTransport) record(kind string, from int, to int, sequence int, delay int64) {
+	t.injected = append(t.injected, InjectedFault{kind, from, to, sequence, delay})
+}
+
+// Takes the next message off the link from source to id. Returns false if there is none.
+// Must be called while holding the deadlock monitor's mutex.
+func (t *faultTransport) take(source int, id int, msg *Message) bool {
+	if link := t.links[source][id]; len(link.queue) > 0 {
+		*msg, link.queue = link.queue[0], link.queue[1:]
+		return true
+	}
+	return false
+}
+
+// Returns true, and marks msg as timed out, if the receive process id is waiting on has timed out.
+// Must be called while holding the deadlock monitor's mutex.
+func (t *faultTransport) timedOut(id int, msg *Message) bool {
+	if !t.expired[id] {
+		return false
+	}
+	t.expired[id] = false
+	*msg = Message{TimedOut: true}
+	return true
+}
+
+// Send puts a message on the link to dest, injecting the link's faults.
+// Returns false if the simulation deadlocked before the message could be sent.
+func (t *faultTransport) Send(id int, dest int, index int, msg Message) bool {
+	return deadlockMonitor.do(id, index, dest, []string{channelObject(id, dest)}, nil, func() bool {
+		t.put(id, dest, msg)
+		return true
+	})
+}
+
+// Receive takes the next message from the link from source, waiting until there is one or the receive times out.
+// Returns false if the simulation deadlocked before either happened.
+func (t *faultTransport) Receive(id int, source int, index int) (Message, bool) {
+	var msg Message
+	received := deadlockMonitor.do(id, index, source, nil, func() bool {
+		return len(t.links[source][id].queue) > 0 || t.expired[id]
+	}, func() bool {
+		return t.take(source, id, &msg) || t.timedOut(id, &msg)
+	})
+	return msg, received
+}
+
+// ReceiveAny takes the next message from the lowest numbered process with a message for process id, waiting until
+// there is one or the receive times out. Returns false if the simulation deadlocked before either happened.
+func (t *faultTransport) ReceiveAny(id int, index int) (int, Message, bool) {
+	var msg Message
+	source := anySource
+	objects := []string{}
+	for i := 0; i < numProcesses; i++ {
+		if i != id {
+			objects = append(objects, channelObject(i, id))
+		}
+	}
+	received := deadlockMonitor.do(id, index, anySource, objects, func() bool {
+		for i := 0; i < numProcesses; i++ {
+			if i != id && len(t.links[i][id].queue) > 0 {
+				return true
+			}
+		}
+		return t.expired[id]
+	}, func() bool {
+		for i := 0; i < numProcesses; i++ {
+			if i != id && t.take(i, id, &msg) {
+				source = i
+				return true
+			}
+		}
+		return t.timedOut(id, &msg)
+	})
+	return source, msg, received
+}
+
+// Close does nothing, since a deadlock is reported by the deadlock monitor.
+func (t *faultTransport) Close() error {
+	return nil
+}
+
+// Called by the deadlock monitor once every process is stuck. Delivers every message still held back, or if there
+// are none, times out every stuck receive. Returns false if neither is possible, and the processes are deadlocked.
+// Timeouts are only decided here, once the whole run is quiet, rather than by each receive after it has waited
+// Timeout ticks, so that which receives time out does not depend on how the goroutines are scheduled.
+// Must be called while holding the deadlock monitor's mutex.
+func (t *faultTransport) quiet(stuck []StuckProcess) bool {
+	released := false
+	for _, links := range t.links {
+		for _, link := range links {
+			for _, h := range link.held {
+				link.queue = append(link.queue, h.messages()...)
+				released = true
+			}
+			link.held = []heldMessage{}
+		}
+	}
+	if released || t.faults.Timeout <= 0 {
+		return released
+	}
+	expired := false
+	for _, p := range stuck {
+		if p.Event.Type == 'R' {
+			t.expired[p.ID] = true
+			expired = true
+		}
+	}
+	return expired
+}
+
+// Records that a receive timed out, as an event of the receiving process after it waited for the timeout.
+func (state *nodeState) timeOut(e Event) {
+	state.trueTime += runConfig.faults.Timeout
+	state.tick()
+	e.TimedOut = true
+	state.record(e)
+}
+
+// Returns a label for a receive that timed out, or an empty string for any other event.
+func timeoutLabel(e Event) string {
+	if !e.TimedOut {
+		return ""
+	}
+	return "timed out"
+}
+
+// Parses fault specs of the form "drop=0.1,duplicate=0.05,delay=0.2,reorder=0.1", giving the probability of each
+// fault. A spec applies to every link, unless it starts with a link, as in "0>1:drop=0.5", in which case it
+// changes the faults of that link from those of every link.
+func parseFaults(specs []string) (Faults, error) {
+	faults := Faults{Links: map[[2]int]LinkFaults{}}
+	linkSpecs := map[[2]int][]string{}
+	links := [][2]int{}
+	for _, spec := range specs {
+		i := strings.Index(spec, ":")
+		if i < 0 {
+			if err := parseLinkFaults(spec, &faults.All); err != nil {
+				return faults, err
+			}
+			continue
+		}
+		var link [2]int
+		var rest string
+		n, _ := fmt.Sscanf(spec[:i]+" end", "%d>%d %s", &link[0], &link[1], &rest)
+		if n != 3 || rest != "end" || link[0] < 0 || link[1] < 0 {
+			return faults, fmt.Errorf("invalid link %q, expected the form <sender>><receiver>, e.g. 0>1", spec[:i])
+		}
+		if _, ok := linkSpecs[link]; !ok {
+			links = append(links, link)
+		}
+		linkSpecs[link] = append(linkSpecs[link], spec[i+1:])
+	}
+	for _, link := range links {
+		linkFaults := faults.All
+		for _, spec := range linkSpecs[link] {
+			if err := parseLinkFaults(spec, &linkFaults); err != nil {
+				return faults, err
+			}
+		}
+		faults.Links[link] = linkFaults
+	}
+	return faults, nil
+}
+
+// Parses a spec of the form "drop=0.1,reorder=0.2" into faults, keeping the probabilities it does not give.
+func parseLinkFaults(spec string, faults *LinkFaults) error {
+	for _, field := range strings.Split(spec, ",") {
+		parts := strings.SplitN(strings.TrimSpace(field), "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid fault %q, expected the form <fault>=<probability>, e.g. drop=0.1", field)
+		}
+		p, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil || p < 0 || p > 1 {
+			return fmt.Errorf("invalid probability %q for %s, expected a number from 0 to 1", parts[1], parts[0])
+		}
+		switch parts[0] {
+		case "drop":
+			faults.Drop = p
+		case "duplicate":
+			faults.Duplicate = p
+		case "delay":
+			faults.Delay = p
+		case "reorder":
+			faults.Reorder = p
+		default:
+			return fmt.Errorf("unknown fault %q, expected drop, duplicate, delay or reorder", parts[0])
+		}
+	}
+	return nil
+}
+
+// Checks that the faults only name links between the processes of the run.
+func validateFaults(faults Faults) error {
+	for link := range faults.Links {
+		if link[0] >= numProcesses || link[1] >= numProcesses || link[0] == link[1] {
+			return fmt.Errorf("faults are given for link %d>%d, which is not a link between two of the %d processes", link[0], link[1], numProcesses)
+		}
+	}
+	delays := faults.All.Delay > 0
+	for _, link := range faults.Links {
+		delays = delays || link.Delay > 0
+	}
+	if delays && faults.MaxDelay <= 0 {
+		return fmt.Errorf("the most ticks a message is delayed by must be positive, but is %d", faults.MaxDelay)
+	}
+	return nil
+}
+
+// BrokenGuarantees lists the events of a run that broke a guarantee of reliable FIFO channels.
+type BrokenGuarantees struct {
+	// S events whose message was never received.
+	Lost []EventID
+	// Receives of a message that had already been received.
+	Duplicates []EventID
+	// Receives of a message sent before a message already received over the same link.
+	OutOfOrder []EventID
+	// Receives of a message whose send happened before an event of the sender the receiver already knew of.
+	// This can also happen over reliable channels, when a later message of the sender reaches the receiver
+	// through other processes first.
+	Causal []EventID
+	// Receives that timed out.
+	TimedOut []EventID
+}
+
+// Returns the events of the last run that broke a guarantee of reliable FIFO channels.
+func brokenGuarantees() BrokenGuarantees {
+	broken := BrokenGuarantees{[]EventID{}, []EventID{}, []EventID{}, []EventID{}, []EventID{}}
+	sent := sentMessages()
+	// The sends whose message was received by any process.
+	delivered := map[EventID]bool{}
+	for j, timelineEvents := range allTimelineEvents {
+		// The sends whose message was received by this process.
+		received := map[EventID]bool{}
+		counts := make([]int, len(allTimelineEvents))
+		latest := make([]int, len(allTimelineEvents))
+		for k, e := range timelineEvents {
+			id := EventID{j, k}
+			if e.Type != 'R' {
+				continue
+			}
+			if e.TimedOut {
+				broken.TimedOut = append(broken.TimedOut, id)
+				continue
+			}
+			counts[e.Peer]++
+			sequence := counts[e.Peer]
+			if e.Sequence > 0 {
+				sequence, counts[e.Peer] = e.Sequence, e.Sequence
+			}
+			send := sent[e.Peer][j][sequence-1]
+			switch {
+			case received[send]:
+				broken.Duplicates = append(broken.Duplicates, id)
+			case sequence < latest[e.Peer]:
+				broken.OutOfOrder = append(broken.OutOfOrder, id)
+			case !e.Broadcast && k > 0 && allClockValues[j][k-1][e.Peer] >= allClockValues[send.Process][send.Index][e.Peer]:
+				broken.Causal = append(broken.Causal, id)
+			}
+			received[send] = true
+			delivered[send] = true
+			if sequence > latest[e.Peer] {
+				latest[e.Peer] = sequence
+			}
+		}
+	}
+	for i, timelineEvents := range allTimelineEvents {
+		for k, e := range timelineEvents {
+			if e.Type == 'S' && !delivered[EventID{i, k}] {
+				broken.Lost = append(broken.Lost, EventID{i, k})
+			}
+		}
+	}
+	return broken
+}
+
+// FaultComparison compares a run with faults injected against the same events run over reliable channels.
+type FaultComparison struct {
+	// The faults injected into the run.
+	Injected []InjectedFault
+	// How the timelines changed from the reliable run.
+	Diff TraceDiff
+	// The guarantees broken in each run.
+	Reliable BrokenGuarantees
+	Faulty   BrokenGuarantees
+}
+
+// Runs the events over reliable channels, then again with the given faults, and compares the runs. The run with
+// faults is left as the last run, and its error, such as a deadlock, is returned along with the comparison.
+// The comparison is nil if the run without faults failed. The run without faults takes its steps in an order picked
+// with the seed of the faults, so it is the same every time, whatever order the run with faults takes.
+func compareFaults(commands [][]string, faults Faults, options ...Option) (*FaultComparison, error) {
+	if err := RunVectorClock(commands, append(options, WithScheduler(sched.New(faults.Seed)))...); err != nil {
+		if _, ok := err.(*DeadlockError); ok {
+			return nil, fmt.Errorf("without faults: %v", err)
+		}
+		return nil, err
+	}
+	reliable := buildTrace()
+	reliableBroken := brokenGuarantees()
+	err := RunVectorClock(commands, append(options, WithFaults(faults))...)
+	diff, diffErr := diffTraces(reliable, buildTrace())
+	if diffErr != nil {
+		return nil, diffErr
+	}
+	return &FaultComparison{allInjectedFaults, diff, reliableBroken, brokenGuarantees()}, err
+}
+
+// Prints a list of events under a title, followed by the number in the reliable run if it is different.
+// Prints nothing if neither run has any, and returns whether it printed the list.
+func printEventList(title string, ids []EventID, reliable []EventID) bool {
+	if len(ids) == 0 && len(reliable) == 0 {
+		return false
+	}
+	names := []string{}
+	for _, id := range ids {
+		names = append(names, id.String())
+	}
+	fmt.Printf("  %s: %d", title, len(ids))
+	if len(ids) > 0 {
+		fmt.Printf(" (%s)", strings.Join(names, " "))
+	}
+	if len(reliable) != len(ids) {
+		fmt.Printf(", %d without faults", len(reliable))
+	}
+	fmt.Printf("\n")
+	return true
+}
+
+// Prints the faults injected into the last run, the guarantees of reliable channels it broke, and how its
+// timelines differ from the run without faults.
+func printFaultComparison(comparison *FaultComparison) {
+	fmt.Printf("Injected faults:\n")
+	sent := sentMessages()
+	for _, fault := range comparison.Injected {
+		fmt.Printf("  the message of %v to P%d was %s", sent[fault.From][fault.To][fault.Sequence-1], fault.To, fault.Kind)
+		if fault.Kind == "delayed" {
+			fmt.Printf(" by %d tick(s)", fault.Delay)
+		}
+		fmt.Printf("\n")
+	}
+	if len(comparison.Injected) == 0 {
+		fmt.Printf("  none\n")
+	}
+	fmt.Printf("Broken guarantees:\n")
+	faulty, reliable := comparison.Faulty, comparison.Reliable
+	printed := printEventList("sends never received", faulty.Lost, reliable.Lost)
+	printed = printEventList("receives of duplicates", faulty.Duplicates, reliable.Duplicates) || printed
+	printed = printEventList("receives out of FIFO order", faulty.OutOfOrder, reliable.OutOfOrder) || printed
+	printed = printEventList("receives out of causal order", faulty.Causal, reliable.Causal) || printed
+	printed = printEventList("receives that timed out", faulty.TimedOut, reliable.TimedOut) || printed
+	if !printed {
+		fmt.Printf("  none\n")
+	}
+	fmt.Printf("Compared with the run without faults:\n")
+	printTraceDiff(comparison.Diff)
+}
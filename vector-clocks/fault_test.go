@@ -0,0 +1,224 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kulvirs/Concurrency-A2/sched"
+)
+
+// Tests that links without faults give the same clocks as reliable channels, and that the same seed injects the
+// same faults into the same run.
+func TestFaultsDeterministic(t *testing.T) {
+	events := [][]string{{"S1", "S1", "S2", "S1", "B"}, {"R0", "R0", "R0", "R0", "S2", "R2"}, {"R0", "R0", "S1", "R1"}}
+	if err := RunVectorClock(events); err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	reliable := allClockValues
+	if err := RunVectorClock(events, WithFaults(Faults{Timeout: 10})); err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	if !reflect.DeepEqual(allClockValues, reliable) {
+		t.Errorf("Expected the clocks %v without faults but got %v", reliable, allClockValues)
+	}
+
+	faults := Faults{All: LinkFaults{0.2, 0.3, 0.3, 0.3}, Seed: 2, MaxDelay: 5, Timeout: 10}
+	RunVectorClock(events, WithFaults(faults))
+	clocks, injected := allClockValues, allInjectedFaults
+	if len(injected) == 0 {
+		t.Fatalf("Expected faults to be injected")
+	}
+	for i := 0; i < 5; i++ {
+		RunVectorClock(events, WithFaults(faults))
+		if !reflect.DeepEqual(allClockValues, clocks) || !reflect.DeepEqual(allInjectedFaults, injected) {
+			t.Fatalf("Expected the same faults and clocks %v every run but got %v", clocks, allClockValues)
+		}
+	}
+}
+
+// Tests that a run with faults and receives from any process is reproduced by the same seed and by its schedule,
+// with receives timing out once the scheduler finds every process blocked.
+func TestFaultsScheduler(t *testing.T) {
+	events := [][]string{{"S1", "S2", "S1", "P"}, {"R0", "R*", "R*"}, {"R0", "S1"}}
+	faults := Faults{All: LinkFaults{0.3, 0.3, 0.3, 0.3}, Seed: 3, MaxDelay: 5, Timeout: 10}
+	s := sched.New(5)
+	if err := RunVectorClock(events, WithFaults(faults), WithScheduler(s)); err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	clocks, injected, schedule := allClockValues, allInjectedFaults, s.Schedule()
+	if len(brokenGuarantees().TimedOut) == 0 {
+		t.Fatalf("Expected a receive to time out")
+	}
+	for run := 0; run < 10; run++ {
+		RunVectorClock(events, WithFaults(faults), WithScheduler(sched.New(5)))
+		if !reflect.DeepEqual(allClockValues, clocks) || !reflect.DeepEqual(allInjectedFaults, injected) {
+			t.Fatalf("Expected the same faults and clocks %v every run but got %v", clocks, allClockValues)
+		}
+	}
+	if err := RunVectorClock(events, WithFaults(faults), WithScheduler(sched.NewReplay(schedule))); err != nil {
+		t.Fatalf("Expected the schedule to be replayed but got %v", err)
+	}
+	if !reflect.DeepEqual(allClockValues, clocks) {
+		t.Errorf("Expected the schedule to replay the clocks %v but got %v", clocks, allClockValues)
+	}
+}
+
+// Tests that a receive of a dropped message times out once no message can arrive, and that without a timeout the
+// run deadlocks instead.
+func TestDropTimesOut(t *testing.T) {
+	events := [][]string{{"S1", "P"}, {"R0", "P"}}
+	faults := Faults{All: LinkFaults{Drop: 1}, Timeout: 10}
+	if err := RunVectorClock(events, WithFaults(faults)); err != nil {
+		t.Fatalf("Expected the receive to time out but got %v", err)
+	}
+	e := allTimelineEvents[1][0]
+	if !e.TimedOut || eventLabel(e) != "timed out" || !reflect.DeepEqual(allClockValues[1], [][]int{{0, 1}, {0, 2}}) {
+		t.Errorf("Expected P1 to time out without merging a clock but got %v (%s) with %v", e, eventLabel(e), allClockValues[1])
+	}
+	broken := brokenGuarantees()
+	if !reflect.DeepEqual(broken.Lost, []EventID{{0, 0}}) || !reflect.DeepEqual(broken.TimedOut, []EventID{{1, 0}}) {
+		t.Errorf("Expected P0.e0 to be lost and P1.e0 to time out but got %+v", broken)
+	}
+
+	faults.Timeout = 0
+	err := RunVectorClock(events, WithFaults(faults))
+	if _, ok := err.(*DeadlockError); !ok {
+		t.Errorf("Expected a deadlock without a timeout but got %v", err)
+	}
+
+	// A receive from any process that times out received from no process.
+	trace := roundTrip(t, [][]string{{"S1"}, {"R*"}}, WithFaults(Faults{All: LinkFaults{Drop: 1}, Timeout: 10}))
+	if e := allTimelineEvents[1][0]; !e.TimedOut || e.Peer != anySource || e.String() != "R*" {
+		t.Errorf("Expected R* to time out without a peer but got %v with peer %d", e, e.Peer)
+	}
+	if err := loadTrace(trace); err != nil {
+		t.Errorf("Expected the trace to load but got %v", err)
+	}
+}
+
+// Tests that duplicated messages are received again, taking the place of the messages after them.
+func TestDuplicate(t *testing.T) {
+	faults := Faults{Links: map[[2]int]LinkFaults{{0, 1}: {Duplicate: 1}}, Timeout: 10}
+	if err := RunVectorClock([][]string{{"S1", "S1", "R1"}, {"R0", "R0", "S0"}}, WithFaults(faults)); err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	if !reflect.DeepEqual(allClockValues[1], [][]int{{1, 1}, {1, 2}, {1, 3}}) {
+		t.Errorf("Expected P1 to receive the first message twice but got %v", allClockValues[1])
+	}
+	broken := brokenGuarantees()
+	if !reflect.DeepEqual(broken.Duplicates, []EventID{{1, 1}}) || !reflect.DeepEqual(broken.Lost, []EventID{{0, 1}}) {
+		t.Errorf("Expected P1.e1 to receive a duplicate and P0.e1 to be lost but got %+v", broken)
+	}
+	if len(broken.TimedOut) != 0 || len(allInjectedFaults) != 2 {
+		t.Errorf("Expected the messages from P1 to be reliable but got %+v and %v", broken, allInjectedFaults)
+	}
+}
+
+// Tests that every reordered message is overtaken by the next message on its link.
+func TestReorder(t *testing.T) {
+	faults := Faults{All: LinkFaults{Reorder: 1}, Timeout: 10}
+	if err := RunVectorClock([][]string{{"S1", "S1", "S1"}, {"R0", "R0", "R0"}}, WithFaults(faults)); err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	sequences := []int{}
+	for _, e := range allTimelineEvents[1] {
+		sequences = append(sequences, e.Sequence)
+	}
+	if !reflect.DeepEqual(sequences, []int{3, 2, 1}) {
+		t.Errorf("Expected the messages to arrive in reverse but got %v", sequences)
+	}
+	broken := brokenGuarantees()
+	if !reflect.DeepEqual(broken.OutOfOrder, []EventID{{1, 1}, {1, 2}}) {
+		t.Errorf("Expected P1.e1 and P1.e2 to be out of order but got %+v", broken)
+	}
+	edges := messageEdges()
+	if len(edges) != 3 || edges[0] != [2]EventID{{0, 2}, {1, 0}} {
+		t.Errorf("Expected the first receive to match the last send but got %v", edges)
+	}
+}
+
+// Tests that a delayed message is overtaken by the messages sent before it arrives, and is received in order
+// after the first message sent once it has arrived.
+func TestDelay(t *testing.T) {
+	defer func(n int) { numProcesses = n }(numProcesses)
+	numProcesses = 2
+	transport := newFaultTransport(Faults{All: LinkFaults{Delay: 1}, MaxDelay: 1})
+	transport.put(0, 1, Message{SentAt: 1, Payload: "a"})
+	transport.faults.All.Delay = 0
+	transport.put(0, 1, Message{SentAt: 1, Payload: "b"})
+	transport.put(0, 1, Message{SentAt: 2, Payload: "c"})
+	payloads := []string{}
+	for _, msg := range transport.links[0][1].queue {
+		payloads = append(payloads, msg.Payload)
+	}
+	if !reflect.DeepEqual(payloads, []string{"b", "a", "c"}) {
+		t.Errorf("Expected b to overtake a, but not c, but got %v", payloads)
+	}
+	if transport.links[0][1].queue[1].Delay != 1 || len(transport.injected) != 1 || transport.injected[0].Kind != "delayed" {
+		t.Errorf("Expected a to be delayed by a tick but got %v and %v", transport.links[0][1].queue, transport.injected)
+	}
+}
+
+// Tests that the run with faults is compared with the run without them.
+func TestCompareFaults(t *testing.T) {
+	events := [][]string{{"S1", "R1"}, {"R0", "S0"}, {"P"}}
+	comparison, err := compareFaults(events, Faults{All: LinkFaults{Drop: 1}, Timeout: 10})
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	if len(comparison.Injected) != 2 || len(comparison.Faulty.TimedOut) != 2 || len(comparison.Reliable.TimedOut) != 0 {
+		t.Errorf("Expected both messages to be dropped and their receives to time out but got %+v", comparison)
+	}
+	changed := []EventChange{{EventID{0, 1}, "R1", "R1 (timed out)"}, {EventID{1, 0}, "R0", "R0 (timed out)"}}
+	if !reflect.DeepEqual(comparison.Diff.Events, changed) {
+		t.Errorf("Expected the changed events %v but got %v", changed, comparison.Diff.Events)
+	}
+	if len(comparison.Diff.Relations) == 0 {
+		t.Errorf("Expected the send and receive to become concurrent")
+	}
+
+	// The run without faults deadlocks if process 1 receives from process 2 first, and takes the same order every time.
+	events = [][]string{{"S1"}, {"R*", "R2"}, {"S1"}}
+	_, first := compareFaults(events, Faults{Seed: 4, Timeout: 10})
+	for run := 0; run < 20; run++ {
+		if _, err := compareFaults(events, Faults{Seed: 4, Timeout: 10}); (err == nil) != (first == nil) {
+			t.Fatalf("Expected the run without faults to end the same way every time, but got %v and then %v", first, err)
+		}
+	}
+
+	_, err = compareFaults([][]string{{"R1", "S1"}, {"R0", "S0"}}, Faults{Timeout: 10})
+	if err == nil || !strings.HasPrefix(err.Error(), "without faults: deadlock detected") {
+		t.Errorf("Expected the run without faults to deadlock but got %v", err)
+	}
+}
+
+// Tests that fault specs are parsed for every link and for single links, and that invalid specs are rejected.
+func TestParseFaults(t *testing.T) {
+	faults, err := parseFaults([]string{"0>1:drop=0.5", "drop=0.1,reorder=0.2", "1>0:delay=1"})
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	if faults.All != (LinkFaults{Drop: 0.1, Reorder: 0.2}) {
+		t.Errorf("Expected the faults of every link to be parsed but got %+v", faults.All)
+	}
+	if faults.link(0, 1) != (LinkFaults{Drop: 0.5, Reorder: 0.2}) || faults.link(1, 0) != (LinkFaults{Drop: 0.1, Delay: 1, Reorder: 0.2}) {
+		t.Errorf("Expected the faults of each link to change those of every link but got %+v", faults.Links)
+	}
+	invalid := map[string]string{
+		"drop":            "invalid fault",
+		"drop=2":          "invalid probability",
+		"lose=0.1":        `unknown fault "lose"`,
+		"0-1:drop=0.1":    "invalid link",
+		"duplicate=x,a=b": "invalid probability",
+	}
+	for spec, expected := range invalid {
+		if _, err := parseFaults([]string{spec}); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected %q to fail with %q but got %v", spec, expected, err)
+		}
+	}
+	err = RunVectorClock([][]string{{"P"}, {"P"}}, WithFaults(Faults{Links: map[[2]int]LinkFaults{{0, 2}: {}}}))
+	if err == nil || !strings.Contains(err.Error(), "link 0>2") {
+		t.Errorf("Expected a link outside the run to be rejected but got %v", err)
+	}
+}
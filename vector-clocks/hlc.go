@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/kulvirs/Concurrency-A2/vector-clocks/vclock"
+)
+
+// PhysicalClock describes a node's simulated physical clock, which reads Skew + t*(1+Drift) at true time t.
+type PhysicalClock struct {
+	// How far ahead (or behind, if negative) the clock is at true time 0.
+	Skew int64
+	// How much faster (or slower, if negative) the clock runs than true time, e.g. 0.1 for 10% fast.
+	Drift float64
+}
+
+// Read returns the time shown by the physical clock at the given true time.
+func (clock PhysicalClock) Read(trueTime int64) int64 {
+	return clock.Skew + int64(math.Floor(float64(trueTime)*(1+clock.Drift)))
+}
+
+// Parses comma separated lists of skews and drifts, one per process, into simulated physical clocks.
+// Either list can be empty or shorter than the other, missing values are 0.
+func parsePhysicalClocks(skews string, drifts string) ([]PhysicalClock, error) {
+	clocks := []PhysicalClock{}
+	at := func(i int) *PhysicalClock {
+		for len(clocks) <= i {
+			clocks = append(clocks, PhysicalClock{})
+		}
+		return &clocks[i]
+	}
+	if skews != "" {
+		for i, value := range strings.Split(skews, ",") {
+			skew, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid skew %q for process %d", value, i)
+			}
+			at(i).Skew = skew
+		}
+	}
+	if drifts != "" {
+		for i, value := range strings.Split(drifts, ",") {
+			drift, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil || drift <= -1 {
+				return nil, fmt.Errorf("invalid drift %q for process %d", value, i)
+			}
+			at(i).Drift = drift
+		}
+	}
+	return clocks, nil
+}
+
+// Returns every event whose hybrid logical clock is more than epsilon ahead of the physical clock of its process.
+func hlcViolations(epsilon int64) []EventID {
+	violations := []EventID{}
+	for i, hlcValues := range allHLCValues {
+		for j, hlc := range hlcValues {
+			if hlc.Physical-allPhysicalValues[i][j] > epsilon {
+				violations = append(violations, EventID{i, j})
+			}
+		}
+	}
+	return violations
+}
+
+// Prints the hybrid logical clock values for each process next to the vector clock and physical clock reading
+// of every event, followed by a check that no hybrid logical clock was more than epsilon ahead of physical time.
+func printHLCValues(epsilon int64) {
+	for i, hlcValues := range allHLCValues {
+		fmt.Printf("%s timeline: ", processTitle(i))
+		for j, hlc := range hlcValues {
+			if j != 0 {
+				fmt.Printf("-> ")
+			}
+			fmt.Printf("%s hlc=%v pt=%d ", vclock.FormatCounters(allClockValues[i][j]), hlc, allPhysicalValues[i][j])
+		}
+		fmt.Printf("\n")
+	}
+
+	violations := hlcViolations(epsilon)
+	if len(violations) == 0 {
+		fmt.Printf("Every hybrid logical clock is within epsilon = %d of physical time.\n", epsilon)
+		return
+	}
+	fmt.Printf("Hybrid logical clocks more than epsilon = %d ahead of physical time:\n", epsilon)
+	for _, id := range violations {
+		hlc := allHLCValues[id.Process][id.Index]
+		fmt.Printf("  %v hlc=%v pt=%d (%d ahead)\n", id, hlc, allPhysicalValues[id.Process][id.Index], hlc.Physical-allPhysicalValues[id.Process][id.Index])
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+)
+
+// Tests that waits advance the physical clock and a skewed sender pulls the receiver's hybrid logical clock ahead.
+func TestHybridClocks(t *testing.T) {
+	// Process 0's physical clock is 10 ticks ahead.
+	p0Events := []string{"P", "S1"}
+	p1Events := []string{"W5", "P", "R0"}
+	events := [][]string{p0Events, p1Events}
+	clocks := []PhysicalClock{{10, 0}}
+
+	if err := RunVectorClock(events, WithHybridClocks(clocks)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Waits do not appear in the timeline.
+	if len(allHLCValues[1]) != 2 || len(allClockValues[1]) != 2 {
+		t.Fatalf("Expected 2 events at process 1, but got %v", allHLCValues[1])
+	}
+	expected := [][]string{{"(11,0)", "(12,0)"}, {"(6,0)", "(12,1)"}}
+	for i, hlcValues := range allHLCValues {
+		for j, hlc := range hlcValues {
+			if hlc.String() != expected[i][j] {
+				t.Errorf("Expected P%d.e%d to be %s, but got %v", i, j, expected[i][j], hlc)
+			}
+		}
+	}
+	// Process 1 receives the message at true time 7, so its hybrid logical clock is 5 ahead of its physical clock.
+	if violations := hlcViolations(5); len(violations) != 0 {
+		t.Errorf("Expected no events more than 5 ahead of physical time, but got %v", violations)
+	}
+	if violations := hlcViolations(4); len(violations) != 1 || violations[0] != (EventID{1, 1}) {
+		t.Errorf("Expected only P1.e1 to be more than 4 ahead of physical time, but got %v", violations)
+	}
+}
+
+// Tests that skews and drifts are parsed per process.
+func TestParsePhysicalClocks(t *testing.T) {
+	clocks, err := parsePhysicalClocks("0,3,-2", "0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []PhysicalClock{{0, 0.1}, {3, 0}, {-2, 0}}
+	if len(clocks) != len(expected) {
+		t.Fatalf("Expected %v, but got %v", expected, clocks)
+	}
+	for i, clock := range clocks {
+		if clock != expected[i] {
+			t.Errorf("Expected %v for process %d, but got %v", expected[i], i, clock)
+		}
+	}
+	if clocks[0].Read(10) != 11 {
+		t.Errorf("Expected a 10%% fast clock to read 11 at time 10, but got %d", clocks[0].Read(10))
+	}
+
+	if _, err := parsePhysicalClocks("1,x", ""); err == nil {
+		t.Errorf("Expected an invalid skew to be rejected, but it was not.")
+	}
+	if _, err := parsePhysicalClocks("", "-1"); err == nil {
+		t.Errorf("Expected a drift of -1 to be rejected, but it was not.")
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/kulvirs/Concurrency-A2/vector-clocks/vclock"
+)
+
+// Stores the initial interval tree clock stamp of every process that is not forked, for the current run.
+var itcSeeds []*vclock.ITC
+
+// Returns the process that forks each process, or -1 for processes that are not forked and start straight away.
+func forkParents(events [][]Event) []int {
+	parents := make([]int, len(events))
+	for i := range parents {
+		parents[i] = -1
+	}
+	for i, processEvents := range events {
+		for _, e := range processEvents {
+			if e.Type == 'F' {
+				parents[e.Peer] = i
+			}
+		}
+	}
+	return parents
+}
+
+// Splits the seed stamp between the initial processes, so that together they own the whole interval.
+func splitSeed(initial []int) []*vclock.ITC {
+	seeds := make([]*vclock.ITC, numProcesses)
+	stamp := vclock.SeedITC()
+	for k, id := range initial {
+		if k == len(initial)-1 {
+			seeds[id] = stamp
+		} else {
+			seeds[id], stamp = stamp.Fork()
+		}
+	}
+	return seeds
+}
+
+// Starts a node with the state a forked process inherits from its parent, without counting it as an event.
+func (state *nodeState) inherit(parent Message) {
+	state.trueTime = parent.SentAt
+	state.clock.Merge(&parent.Clock)
+	if state.matrix != nil {
+		state.matrix.Merge(parent.Matrix)
+	}
+	if state.lamport != nil {
+		state.lamport.Merge(parent.Lamport)
+	}
+	if state.hlc != nil {
+		state.hlc.Physical, state.hlc.Logical = parent.HLC.Physical, parent.HLC.Logical
+	}
+	if runConfig.itc {
+		state.itc = parent.ITC
+	}
+	if state.plausible != nil {
+		state.plausible.Merge(parent.Plausible)
+	}
+	if state.bloom != nil {
+		state.bloom.Merge(parent.Bloom)
+	}
+}
+
+// Forks a new process, which starts running its events with a copy of this node's clocks.
+// The node's interval tree clock stamp is split in two, with one half handed to the new process.
+func (state *nodeState) fork(e Event, wg *sync.WaitGroup) {
+	state.tick()
+	child := state.message()
+	if state.itc != nil {
+		state.itc, child.ITC = state.itc.Fork()
+	}
+	state.record(e)
+	deadlockMonitor.start(e.Peer)
+	wg.Add(1)
+	go node(e.Peer, &child, wg)
+}
+
+// Waits for a process to finish its events, then joins its final state into this node's, retiring it.
+// Returns false if the simulation deadlocked before the process finished.
+func (state *nodeState) join(e Event, index int) bool {
+	id := state.clock.ID
+	var exit Message
+	finished := deadlockMonitor.do(id, index, e.Peer, []string{fmt.Sprintf("exit %d", e.Peer)}, func() bool {
+		return len(exits[e.Peer]) > 0
+	}, func() bool {
+		select {
+		case exit = <-exits[e.Peer]:
+			return true
+		default:
+			return false
+		}
+	})
+	if !finished {
+		return false
+	}
+	state.merge(exit)
+	state.record(e)
+	return true
+}
+
+// Returns a label describing a fork or join event in a timeline, or an empty string for any other event.
+func forkLabel(e Event) string {
+	switch e.Type {
+	case 'F':
+		return fmt.Sprintf("fork P%d", e.Peer)
+	case 'J':
+		return fmt.Sprintf("join P%d", e.Peer)
+	}
+	return ""
+}
+
+// Prints the interval tree clock stamps for each process, labelling forks and joins.
+func printITCValues() {
+	parents := forkParents(scriptEvents)
+	for i, itcValues := range allITCValues {
+		fmt.Printf("%s timeline", processTitle(i))
+		if parents[i] >= 0 {
+			fmt.Printf(" (forked by P%d)", parents[i])
+		}
+		fmt.Printf(": ")
+		for j, stamp := range itcValues {
+			if j != 0 {
+				fmt.Printf("-> ")
+			}
+			fmt.Printf("%v ", stamp)
+			if label := forkLabel(allTimelineEvents[i][j]); label != "" {
+				fmt.Printf("(%s) ", label)
+			}
+		}
+		fmt.Printf("\n")
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// Tests that a forked process only starts when it is forked, and that joining it retires its id.
+func TestForkJoin(t *testing.T) {
+	// Process 0 forks process 2, which sends to process 1 before process 0 joins it.
+	p0Events := []string{"P0", "F2", "P0", "J2", "P0"}
+	p1Events := []string{"P1", "R2"}
+	p2Events := []string{"P2", "S1"}
+	events := [][]string{p0Events, p1Events, p2Events}
+
+	if err := RunVectorClock(events, WithIntervalTreeClocks()); err != nil {
+		t.Fatal(err)
+	}
+
+	// The fork happens before everything process 2 does, and everything process 2 does happens before the join.
+	expectedOrders := [][4]int{[4]int{0, 1, 2, 0}, [4]int{2, 1, 1, 1}, [4]int{2, 1, 0, 3}}
+	for _, expectedOrder := range expectedOrders {
+		if verifyOrder(expectedOrder) == false {
+			t.Errorf("Expected Process %d Event %d to come before Process %d Event %d, but it did not.", expectedOrder[0], expectedOrder[1], expectedOrder[2], expectedOrder[3])
+		}
+	}
+	// The stamps must agree with the vector clocks.
+	fork, child, join := allITCValues[0][1], allITCValues[2][1], allITCValues[0][3]
+	if !fork.Leq(child) || !child.Leq(join) || join.Leq(child) {
+		t.Errorf("Expected %v <= %v <= %v, but they were not.", fork, child, join)
+	}
+	if allITCValues[1][0].Leq(allITCValues[0][4]) || allITCValues[0][4].Leq(allITCValues[1][1]) {
+		t.Errorf("Expected P1.e0 %v and P0.e4 %v to be concurrent, but they were not.", allITCValues[1][0], allITCValues[0][4])
+	}
+	// After the join, process 0 owns process 2's half of the interval again.
+	if !strings.HasPrefix(allITCValues[0][4].String(), "((1,0), ") {
+		t.Errorf("Expected process 0 to own half the interval after joining process 2, but got %v", allITCValues[0][4])
+	}
+}
+
+// Tests that forks that can never happen are rejected before the run.
+func TestValidateForks(t *testing.T) {
+	invalid := map[string][][]string{
+		"forked more than once": {{"F2"}, {"F2"}, {"P"}},
+		"joined more than once": {{"J2"}, {"J2"}, {"P"}},
+		"cycle":                 {{"P"}, {"F2"}, {"F1"}},
+	}
+	for expected, events := range invalid {
+		err := RunVectorClock(events)
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected an error containing %q for %v, but got %v", expected, events, err)
+		}
+	}
+}
+
+// Tests that a process waiting to join a process that never finishes is reported as deadlocked.
+func TestJoinDeadlock(t *testing.T) {
+	p0Events := []string{"F1", "J1", "S1"}
+	p1Events := []string{"R0"}
+	events := [][]string{p0Events, p1Events}
+
+	err := RunVectorClock(events)
+	if _, ok := err.(*DeadlockError); !ok || !strings.Contains(err.Error(), "waiting for process 1 to finish") {
+		t.Errorf("Expected process 0 to be stuck waiting for process 1 to finish, but got %v", err)
+	}
+}
+
+// Tests that a process that joins a process straight after forking it waits for it to finish, rather than being
+// reported as deadlocked when the forked process finishes while it waits.
+func TestJoinAfterFork(t *testing.T) {
+	for i := 0; i < 50; i++ {
+		if err := RunVectorClock([][]string{{"F1", "J1", "P"}, {"P"}}); err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+	}
+}
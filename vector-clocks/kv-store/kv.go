@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kulvirs/Concurrency-A2/vector-clocks/vclock"
+)
+
+// Version represents one version of the value of a key, stored at a replica.
+type Version struct {
+	// The value written.
+	Value string
+	// The dotted version vector of the write.
+	Clock vclock.DVV
+	// The step of the script the write happened at, used as the timestamp for last-writer-wins.
+	Time int
+}
+
+// String returns the version in the form value(replica,counter)[context].
+func (v Version) String() string {
+	return v.Value + v.Clock.String()
+}
+
+// Replica represents one replica of the key-value store.
+type Replica struct {
+	// The replica's id.
+	ID int
+	// Stores the sibling versions of each key.
+	Data map[string][]Version
+	// Stores the context returned by the last GET of each key, used by the next PUT with CTX.
+	Contexts map[string][]int
+	// Stores the single version of each key that a last-writer-wins store would keep.
+	LWW map[string]Version
+}
+
+// Conflict records a key that had concurrent versions after a step of the script.
+type Conflict struct {
+	// The step of the script the conflict was detected at.
+	Step int
+	// The replica the conflict was detected at.
+	Replica int
+	// The key in conflict.
+	Key string
+	// The concurrent versions of the key.
+	Siblings []Version
+	// The version last-writer-wins would keep.
+	Winner Version
+}
+
+// NewReplica returns an empty replica with the given id.
+func NewReplica(id int) *Replica {
+	return &Replica{id, map[string][]Version{}, map[string][]int{}, map[string]Version{}}
+}
+
+// Get returns the sibling versions of a key and their context, which is remembered for the next PUT with CTX.
+func (r *Replica) Get(key string) ([]Version, []int) {
+	siblings := r.Data[key]
+	context := vclock.Context(clocksOf(siblings))
+	r.Contexts[key] = context
+	return siblings, context
+}
+
+// Put writes a value for a key with the given context at the given time. Every sibling the context includes
+// is replaced by the new version, any others are kept alongside it.
+func (r *Replica) Put(key string, value string, context []int, time int) Version {
+	siblings := r.Data[key]
+	version := Version{value, vclock.Write(clocksOf(siblings), context, r.ID), time}
+	r.Data[key] = keep(append(append([]Version{}, siblings...), version))
+	if lww, ok := r.LWW[key]; !ok || lww.Time < time {
+		r.LWW[key] = version
+	}
+	return version
+}
+
+// Receive merges the versions of a key sent by another replica into this replica's versions.
+func (r *Replica) Receive(key string, versions []Version, lww Version) {
+	r.Data[key] = keep(append(append([]Version{}, r.Data[key]...), versions...))
+	if current, ok := r.LWW[key]; !ok || current.Time < lww.Time {
+		r.LWW[key] = lww
+	}
+}
+
+// Returns the clock of every version.
+func clocksOf(versions []Version) []vclock.DVV {
+	clocks := make([]vclock.DVV, len(versions))
+	for i, v := range versions {
+		clocks[i] = v.Clock
+	}
+	return clocks
+}
+
+// Returns the versions left once duplicates and obsolete versions are dropped.
+func keep(versions []Version) []Version {
+	kept := []Version{}
+	for _, i := range vclock.Sync(clocksOf(versions)) {
+		kept = append(kept, versions[i])
+	}
+	return kept
+}
+
+// Runs a script against a store with the given number of replicas, writing the result of every step to w.
+// Each line of the script is one of:
+//
+//	<replica> PUT <key> <value>      blind write, with an empty context
+//	<replica> PUT <key> <value> CTX  write with the context of the replica's last GET of the key
+//	<replica> GET <key>              read every sibling of the key
+//	<replica> SYNC <other>           send every key to another replica
+//
+// Returns every conflict that was detected.
+func runStore(numReplicas int, script []string, w io.Writer) ([]Conflict, error) {
+	replicas := make([]*Replica, numReplicas)
+	for i := range replicas {
+		replicas[i] = NewReplica(i)
+	}
+	conflicts := []Conflict{}
+	// Checks a key at a replica for siblings after a step.
+	check := func(step int, r *Replica, key string) {
+		siblings := r.Data[key]
+		if len(siblings) < 2 {
+			return
+		}
+		conflict := Conflict{step, r.ID, key, siblings, r.LWW[key]}
+		conflicts = append(conflicts, conflict)
+		lost := []string{}
+		for _, v := range siblings {
+			if v.Clock.Dot != conflict.Winner.Clock.Dot {
+				lost = append(lost, v.Value)
+			}
+		}
+		fmt.Fprintf(w, "  conflict on %s at replica %d: %v, last-writer-wins would keep %s and silently lose %s\n",
+			key, r.ID, siblings, conflict.Winner.Value, strings.Join(lost, ", "))
+	}
+
+	for step, line := range script {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			return conflicts, fmt.Errorf("line %d: invalid command: %s", step+1, line)
+		}
+		id, err := strconv.Atoi(fields[0])
+		if err != nil || id < 0 || id >= numReplicas {
+			return conflicts, fmt.Errorf("line %d: invalid replica: %s", step+1, fields[0])
+		}
+		r := replicas[id]
+		fmt.Fprintf(w, "%s\n", line)
+
+		switch {
+		case fields[1] == "GET" && len(fields) == 3:
+			siblings, context := r.Get(fields[2])
+			fmt.Fprintf(w, "  %s = %v, context %s\n", fields[2], siblings, vclock.FormatCounters(context))
+		case fields[1] == "PUT" && (len(fields) == 4 || (len(fields) == 5 && fields[4] == "CTX")):
+			context := []int{}
+			if len(fields) == 5 {
+				context = r.Contexts[fields[2]]
+			}
+			version := r.Put(fields[2], fields[3], context, step)
+			fmt.Fprintf(w, "  wrote %v\n", version)
+			check(step, r, fields[2])
+		case fields[1] == "SYNC" && len(fields) == 3:
+			other, err := strconv.Atoi(fields[2])
+			if err != nil || other < 0 || other >= numReplicas || other == id {
+				return conflicts, fmt.Errorf("line %d: invalid replica: %s", step+1, fields[2])
+			}
+			keys := []string{}
+			for key := range r.Data {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				replicas[other].Receive(key, r.Data[key], r.LWW[key])
+				fmt.Fprintf(w, "  %s at replica %d = %v\n", key, other, replicas[other].Data[key])
+				check(step, replicas[other], key)
+			}
+		default:
+			return conflicts, fmt.Errorf("line %d: invalid command: %s", step+1, line)
+		}
+	}
+	return conflicts, nil
+}
+
+// Runs the key-value store by reading the script from stdin. The first line is the number of replicas.
+func main() {
+	scanner := bufio.NewScanner(os.Stdin)
+	lines := []string{}
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		log.Fatal(err)
+	}
+	if len(lines) == 0 {
+		log.Fatal("The first line should be the number of replicas")
+	}
+
+	numReplicas, err := strconv.Atoi(lines[0])
+	if err != nil || numReplicas < 1 {
+		// The first line should be an integer indicating the number of replicas.
+		log.Fatal("The first line should be the number of replicas")
+	}
+	conflicts, err := runStore(numReplicas, lines[1:], os.Stdout)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%d conflict(s) detected that last-writer-wins would have resolved by silently losing writes\n", len(conflicts))
+}
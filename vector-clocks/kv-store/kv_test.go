@@ -0,0 +1,67 @@
+package main
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+// Tests that concurrent writes at different replicas are detected as a conflict that last-writer-wins would lose.
+func TestConcurrentWritesConflict(t *testing.T) {
+	script := []string{
+		"0 PUT x a",
+		"1 PUT x b",
+		"0 SYNC 1",
+	}
+	conflicts, err := runStore(2, script, ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conflicts) != 1 || len(conflicts[0].Siblings) != 2 {
+		t.Fatalf("Expected one conflict with two siblings, but got %v", conflicts)
+	}
+	if conflicts[0].Winner.Value != "b" {
+		t.Errorf("Expected last-writer-wins to keep the later write b, but it kept %s", conflicts[0].Winner.Value)
+	}
+}
+
+// Tests that a write with the context of a read replaces every sibling that was read.
+func TestResolveWithContext(t *testing.T) {
+	script := []string{
+		"0 PUT x a",
+		"0 PUT x b",
+		"0 GET x",
+		"0 PUT x ab CTX",
+		"0 SYNC 1",
+	}
+	conflicts, err := runStore(2, script, ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The two blind writes to replica 0 conflict, but the write back resolves them.
+	if len(conflicts) != 1 || conflicts[0].Step != 1 {
+		t.Errorf("Expected only the blind writes to conflict, but got %v", conflicts)
+	}
+}
+
+// Tests that a write after syncing replaces the synced version instead of conflicting with it.
+func TestCausalWriteNoConflict(t *testing.T) {
+	script := []string{
+		"0 PUT x a",
+		"0 SYNC 1",
+		"1 GET x",
+		"1 PUT x b CTX",
+		"1 SYNC 0",
+		"0 GET x",
+	}
+	conflicts, err := runStore(2, script, ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conflicts) != 0 {
+		t.Errorf("Expected no conflicts, but got %v", conflicts)
+	}
+
+	if _, err := runStore(2, []string{"2 GET x"}, ioutil.Discard); err == nil {
+		t.Errorf("Expected an invalid replica to be rejected, but it was not.")
+	}
+}
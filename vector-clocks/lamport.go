@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/kulvirs/Concurrency-A2/vector-clocks/vclock"
+)
+
+// Returns the Lamport clock recorded for an event.
+func lamportValue(id EventID) *vclock.LamportClock {
+	return allLamportValues[id.Process][id.Index]
+}
+
+// Returns every recorded event in the total order given by their Lamport timestamps.
+func lamportOrder() []EventID {
+	ids := allEventIDs()
+	sort.Slice(ids, func(a, b int) bool {
+		return lamportValue(ids[a]).Less(lamportValue(ids[b]))
+	})
+	return ids
+}
+
+// Returns every pair of events that the vector clocks report as concurrent, with the event the Lamport clocks
+// order first at index 0.
+func lamportOrderedConcurrentPairs() [][2]EventID {
+	pairs := concurrentPairs()
+	for i, pair := range pairs {
+		if lamportValue(pair[1]).Less(lamportValue(pair[0])) {
+			pairs[i] = [2]EventID{pair[1], pair[0]}
+		}
+	}
+	return pairs
+}
+
+// Prints the Lamport clock values for each process, the total order of every event, and the pairs of events
+// that the Lamport clocks order even though the vector clocks report them as concurrent.
+func printLamportValues() {
+	for i, lamportValues := range allLamportValues {
+		fmt.Printf("%s timeline: ", processTitle(i))
+		for j, lamportValue := range lamportValues {
+			if j != 0 {
+				fmt.Printf("-> ")
+			}
+			fmt.Printf("%v ", lamportValue)
+		}
+		fmt.Printf("\n")
+	}
+
+	fmt.Printf("Total order:")
+	for _, id := range lamportOrder() {
+		fmt.Printf(" %v%v", id, lamportValue(id))
+	}
+	fmt.Printf("\n")
+
+	fmt.Printf("Ordered by Lamport clocks but concurrent by vector clocks:\n")
+	for _, pair := range lamportOrderedConcurrentPairs() {
+		fmt.Printf("  %v%v < %v%v, but %s || %s\n", pair[0], lamportValue(pair[0]), pair[1], lamportValue(pair[1]),
+			vclock.FormatCounters(allClockValues[pair[0].Process][pair[0].Index]), vclock.FormatCounters(allClockValues[pair[1].Process][pair[1].Index]))
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+)
+
+// Tests that the Lamport total order is consistent with happens-before and breaks ties by process id.
+func TestLamportOrder(t *testing.T) {
+	p0Events := []string{"P0", "S1"}
+	p1Events := []string{"P1", "R0"}
+	p2Events := []string{"P2"}
+	events := [][]string{p0Events, p1Events, p2Events}
+
+	if err := RunVectorClock(events, WithLamportClocks()); err != nil {
+		t.Fatal(err)
+	}
+
+	// The three first events all have time 1, and the send (time 2) comes before the receive (time 3).
+	expected := []EventID{{0, 0}, {1, 0}, {2, 0}, {0, 1}, {1, 1}}
+	order := lamportOrder()
+	if len(order) != len(expected) {
+		t.Fatalf("Expected the total order %v, but got %v", expected, order)
+	}
+	for i, id := range order {
+		if id != expected[i] {
+			t.Errorf("Expected the total order %v, but got %v", expected, order)
+			break
+		}
+	}
+
+	// Every concurrent pair is ordered by the Lamport clocks, e.g. P2.e0 (1,2) comes before P0.e1 (2,0).
+	found := false
+	for _, pair := range lamportOrderedConcurrentPairs() {
+		if !lamportValue(pair[0]).Less(lamportValue(pair[1])) {
+			t.Errorf("Expected %v to be ordered before %v by the Lamport clocks, but it was not.", pair[0], pair[1])
+		}
+		if pair == [2]EventID{{2, 0}, {0, 1}} {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected P2.e0 and P0.e1 to be concurrent but ordered by the Lamport clocks, but they were not.")
+	}
+}
@@ -0,0 +1,405 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Global predicate detection over the lattice of consistent global states, in the style of Cooper-Marzullo.
+// A global state is a cut, written (c0,c1,...), where ci is the number of events process i has run. A cut is
+// consistent if every event inside it only knows of events that are also inside it. The initial cut (0,...,0)
+// and the final cut, where every process has run all of its events, are always consistent, and every run of
+// the processes passes through a path of consistent cuts from one to the other, running one event at a time.
+
+// Returns the number of events of process i that the event at the end of its cut knows of, which is its own
+// entry in the vector clock of that event.
+func ownCount(i int, count int) int {
+	if count == 0 {
+		return 0
+	}
+	return allClockValues[i][count-1][i]
+}
+
+// Returns true if no process in the cut knows of an event of another process outside the cut.
+func consistentCut(cut []int) bool {
+	for i, count := range cut {
+		if count == 0 {
+			continue
+		}
+		clock := allClockValues[i][count-1]
+		for j := range cut {
+			if j != i && clock[j] > ownCount(j, cut[j]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Returns the consistent cuts reachable from cut by running one more event at one process.
+func nextCuts(cut []int) [][]int {
+	next := [][]int{}
+	for i := range cut {
+		if cut[i] == len(allClockValues[i]) {
+			continue
+		}
+		successor := append([]int{}, cut...)
+		successor[i]++
+		if consistentCut(successor) {
+			next = append(next, successor)
+		}
+	}
+	return next
+}
+
+// Returns the cut in the form (c0,c1,...).
+func formatCut(cut []int) string {
+	counts := make([]string, len(cut))
+	for i, count := range cut {
+		counts[i] = strconv.Itoa(count)
+	}
+	return "(" + strings.Join(counts, ",") + ")"
+}
+
+// Returns every consistent global state, grouped into levels by the total number of events run.
+func latticeLevels() [][][]int {
+	levels := [][][]int{[][]int{make([]int, len(allClockValues))}}
+	for {
+		seen := map[string]bool{}
+		level := [][]int{}
+		for _, cut := range levels[len(levels)-1] {
+			for _, next := range nextCuts(cut) {
+				if !seen[formatCut(next)] {
+					seen[formatCut(next)] = true
+					level = append(level, next)
+				}
+			}
+		}
+		if len(level) == 0 {
+			return levels
+		}
+		levels = append(levels, level)
+	}
+}
+
+// Returns the local variables of every process after each of its events. Element [i][k] holds the variables of
+// process i after its first k events. Variables that have not been assigned are 0.
+func localVariables() [][]map[string]int {
+	variables := make([][]map[string]int, len(allTimelineEvents))
+	for i, events := range allTimelineEvents {
+		current := map[string]int{}
+		variables[i] = []map[string]int{current}
+		for _, e := range events {
+			if e.Assign != "" {
+				next := map[string]int{}
+				for name, value := range current {
+					next[name] = value
+				}
+				for _, assignment := range strings.Fields(e.Assign) {
+					name, value, _ := parseAssignment(assignment)
+					next[name] = value
+				}
+				current = next
+			}
+			variables[i] = append(variables[i], current)
+		}
+	}
+	return variables
+}
+
+// Predicate is a condition on the local variables of the processes in a global state, such as
+// "P0.x + P1.y > 5 && P2.done == 1". Comparisons and the logical operators give 1 for true and 0 for false,
+// and any value other than 0 counts as true.
+type Predicate struct {
+	// The predicate as it was written.
+	Text string
+	// Evaluates the predicate given the local variables of every process.
+	eval func(variables []map[string]int) int
+}
+
+// Holds returns true if the predicate holds in the global state given by cut.
+func (p *Predicate) Holds(variables [][]map[string]int, cut []int) bool {
+	state := make([]map[string]int, len(cut))
+	for i, count := range cut {
+		state[i] = variables[i][count]
+	}
+	return p.eval(state) != 0
+}
+
+// Splits a predicate into tokens: integers, variables of the form P<process>.<name> or <process name>.<name>,
+// parentheses and operators.
+func tokenizePredicate(text string) ([]string, error) {
+	tokens := []string{}
+	for i := 0; i < len(text); {
+		if text[i] == ' ' {
+			i++
+			continue
+		}
+		start := i
+		for _, op := range []string{"==", "!=", "<=", ">=", "&&", "||"} {
+			if strings.HasPrefix(text[i:], op) {
+				i += len(op)
+				break
+			}
+		}
+		if i == start && strings.ContainsRune("()+-*<>!", rune(text[i])) {
+			i++
+		}
+		// Integers and variables run until the next space, parenthesis or operator.
+		for i < len(text) && isWordByte(text[i]) && (i == start || isWordByte(text[start])) {
+			i++
+		}
+		if i == start {
+			return nil, fmt.Errorf("invalid predicate %q: unexpected %q", text, text[i])
+		}
+		tokens = append(tokens, text[start:i])
+	}
+	return tokens, nil
+}
+
+// Returns true if c can be part of an integer or a variable.
+func isWordByte(c byte) bool {
+	return c == '_' || c == '.' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// Parses predicates by recursive descent. From lowest to highest precedence the operators are ||, &&,
+// the comparisons, + and -, *, and then unary ! and -.
+type predicateParser struct {
+	text   string
+	tokens []string
+	pos    int
+	// The number of processes the predicate can refer to.
+	processes int
+	// The number of each named process.
+	numbers map[string]int
+}
+
+// Returns the next token without consuming it, or an empty string at the end of the predicate.
+func (p *predicateParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+// Parses a chain of binary operators at one level of precedence, where operand parses the level above.
+func (p *predicateParser) binary(operators map[string]func(a, b int) int, operand func() (func([]map[string]int) int, error)) (func([]map[string]int) int, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := operators[p.peek()]
+		if !ok {
+			return left, nil
+		}
+		p.pos++
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(state []map[string]int) int {
+			return op(l(state), right(state))
+		}
+	}
+}
+
+// Returns 1 if b is true and 0 otherwise.
+func truth(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func (p *predicateParser) or() (func([]map[string]int) int, error) {
+	return p.binary(map[string]func(a, b int) int{
+		"||": func(a, b int) int { return truth(a != 0 || b != 0) },
+	}, p.and)
+}
+
+func (p *predicateParser) and() (func([]map[string]int) int, error) {
+	return p.binary(map[string]func(a, b int) int{
+		"&&": func(a, b int) int { return truth(a != 0 && b != 0) },
+	}, p.comparison)
+}
+
+func (p *predicateParser) comparison() (func([]map[string]int) int, error) {
+	return p.binary(map[string]func(a, b int) int{
+		"==": func(a, b int) int { return truth(a == b) },
+		"!=": func(a, b int) int { return truth(a != b) },
+		"<":  func(a, b int) int { return truth(a < b) },
+		"<=": func(a, b int) int { return truth(a <= b) },
+		">":  func(a, b int) int { return truth(a > b) },
+		">=": func(a, b int) int { return truth(a >= b) },
+	}, p.sum)
+}
+
+func (p *predicateParser) sum() (func([]map[string]int) int, error) {
+	return p.binary(map[string]func(a, b int) int{
+		"+": func(a, b int) int { return a + b },
+		"-": func(a, b int) int { return a - b },
+	}, p.product)
+}
+
+func (p *predicateParser) product() (func([]map[string]int) int, error) {
+	return p.binary(map[string]func(a, b int) int{
+		"*": func(a, b int) int { return a * b },
+	}, p.unary)
+}
+
+// Parses a unary ! or -, a parenthesized predicate, an integer or a variable.
+func (p *predicateParser) unary() (func([]map[string]int) int, error) {
+	token := p.peek()
+	p.pos++
+	switch {
+	case token == "!" || token == "-":
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		if token == "!" {
+			return func(state []map[string]int) int { return truth(operand(state) == 0) }, nil
+		}
+		return func(state []map[string]int) int { return -operand(state) }, nil
+	case token == "(":
+		inner, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("invalid predicate %q: missing )", p.text)
+		}
+		p.pos++
+		return inner, nil
+	case token != "" && token[0] >= '0' && token[0] <= '9':
+		value, err := strconv.Atoi(token)
+		if err != nil {
+			return nil, fmt.Errorf("invalid predicate %q: invalid integer %s", p.text, token)
+		}
+		return func(state []map[string]int) int { return value }, nil
+	case token != "" && (token[0] == 'P' || strings.Contains(token, ".")):
+		fields := strings.SplitN(token, ".", 2)
+		process, ok := p.numbers[fields[0]]
+		if !ok && strings.HasPrefix(fields[0], "P") {
+			number, err := strconv.Atoi(fields[0][1:])
+			process, ok = number, err == nil
+		}
+		if !ok || len(fields) != 2 || !validVariable(fields[1]) {
+			return nil, fmt.Errorf("invalid predicate %q: invalid variable %s, expected the form P<process>.<name> or <process name>.<name>", p.text, token)
+		}
+		if process < 0 || process >= p.processes {
+			return nil, fmt.Errorf("invalid predicate %q: no process %d", p.text, process)
+		}
+		name := fields[1]
+		return func(state []map[string]int) int { return state[process][name] }, nil
+	case token == "":
+		return nil, fmt.Errorf("invalid predicate %q: unexpected end", p.text)
+	}
+	return nil, fmt.Errorf("invalid predicate %q: unexpected %s", p.text, token)
+}
+
+// Parses a predicate over the local variables of the given number of processes, which it can refer to by the
+// names in numbers as well as by number.
+func parsePredicate(text string, processes int, numbers map[string]int) (*Predicate, error) {
+	tokens, err := tokenizePredicate(text)
+	if err != nil {
+		return nil, err
+	}
+	p := &predicateParser{text: text, tokens: tokens, processes: processes, numbers: numbers}
+	eval, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(tokens) {
+		return nil, fmt.Errorf("invalid predicate %q: unexpected %s", text, p.peek())
+	}
+	return &Predicate{text, eval}, nil
+}
+
+// Possibly returns a consistent global state in which the predicate holds, or nil if there is none.
+// If the predicate possibly holds, some run of the processes passes through a state in which it holds.
+func Possibly(p *Predicate) []int {
+	variables := localVariables()
+	for _, level := range latticeLevels() {
+		for _, cut := range level {
+			if p.Holds(variables, cut) {
+				return cut
+			}
+		}
+	}
+	return nil
+}
+
+// Definitely returns nil if the predicate definitely holds: every run of the processes passes through a
+// consistent global state in which it holds. Otherwise it returns a path of consistent global states from the
+// initial state to the final state in which the predicate never holds.
+func Definitely(p *Predicate) [][]int {
+	variables := localVariables()
+	initial := make([]int, len(allClockValues))
+	if p.Holds(variables, initial) {
+		return nil
+	}
+	// Search level by level through the states that can be reached without the predicate holding.
+	parents := map[string][]int{formatCut(initial): nil}
+	level := [][]int{initial}
+	for len(level) > 0 {
+		next := [][]int{}
+		for _, cut := range level {
+			successors := nextCuts(cut)
+			if len(successors) == 0 {
+				// The final state was reached, so follow the parents back to the initial state.
+				path := [][]int{cut}
+				for parent := parents[formatCut(cut)]; parent != nil; parent = parents[formatCut(parent)] {
+					path = append([][]int{parent}, path...)
+				}
+				return path
+			}
+			for _, successor := range successors {
+				if _, seen := parents[formatCut(successor)]; !seen && !p.Holds(variables, successor) {
+					parents[formatCut(successor)] = cut
+					next = append(next, successor)
+				}
+			}
+		}
+		level = next
+	}
+	return nil
+}
+
+// Prints every consistent global state, one level per line.
+func printLattice() {
+	levels := latticeLevels()
+	total := 0
+	for _, level := range levels {
+		total += len(level)
+	}
+	fmt.Printf("Consistent global states (%d):\n", total)
+	for l, level := range levels {
+		fmt.Printf("  level %d:", l)
+		for _, cut := range level {
+			fmt.Printf(" %s", formatCut(cut))
+		}
+		fmt.Printf("\n")
+	}
+}
+
+// Prints whether a predicate possibly and definitely holds, with a global state or path that shows why.
+func printPredicate(p *Predicate) {
+	if cut := Possibly(p); cut != nil {
+		fmt.Printf("Possibly(%s): yes, at %s\n", p.Text, formatCut(cut))
+	} else {
+		fmt.Printf("Possibly(%s): no\n", p.Text)
+	}
+	if path := Definitely(p); path != nil {
+		cuts := make([]string, len(path))
+		for i, cut := range path {
+			cuts[i] = formatCut(cut)
+		}
+		fmt.Printf("Definitely(%s): no, it never holds along %s\n", p.Text, strings.Join(cuts, " -> "))
+	} else {
+		fmt.Printf("Definitely(%s): yes\n", p.Text)
+	}
+}
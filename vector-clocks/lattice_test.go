@@ -0,0 +1,122 @@
+package main
+
+import (
+	"testing"
+)
+
+// Runs the events and parses the predicate, failing the test if either fails.
+func runPredicate(t *testing.T, events [][]string, text string) *Predicate {
+	if err := RunVectorClock(events); err != nil {
+		t.Fatal(err)
+	}
+	predicate, err := parsePredicate(text, len(allClockValues), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return predicate
+}
+
+// Tests that the lattice contains exactly the consistent cuts of a run with one message.
+func TestLatticeLevels(t *testing.T) {
+	events := [][]string{[]string{"P", "S1", "P"}, []string{"P", "R0", "P"}}
+	if err := RunVectorClock(events); err != nil {
+		t.Fatal(err)
+	}
+	levels := latticeLevels()
+	total := 0
+	for _, level := range levels {
+		total += len(level)
+		for _, cut := range level {
+			// The receive at process 1 can only be in a cut that includes the send at process 0.
+			if cut[1] >= 2 && cut[0] < 2 {
+				t.Errorf("Expected %s to be inconsistent, but it was in the lattice", formatCut(cut))
+			}
+		}
+	}
+	if len(levels) != 7 || total != 12 {
+		t.Errorf("Expected 12 consistent global states in 7 levels, but got %d in %d levels", total, len(levels))
+	}
+}
+
+// Tests that a predicate that only holds between two concurrent events possibly but not definitely holds.
+func TestPossiblyNotDefinitely(t *testing.T) {
+	events := [][]string{[]string{"P", "x=1", "P", "x=0"}, []string{"P", "y=1", "P", "y=0"}}
+	predicate := runPredicate(t, events, "P0.x == 1 && P1.y == 1")
+
+	if cut := Possibly(predicate); cut == nil || cut[0] != 1 || cut[1] != 1 {
+		t.Errorf("Expected the predicate to possibly hold at (1,1), but got %v", cut)
+	}
+	path := Definitely(predicate)
+	if path == nil {
+		t.Fatalf("Expected the predicate not to definitely hold, but it did.")
+	}
+	for _, cut := range path {
+		if cut[0] == 1 && cut[1] == 1 {
+			t.Errorf("Expected the path to avoid (1,1), but got %v", path)
+		}
+	}
+}
+
+// Tests that a predicate every run passes through definitely holds.
+func TestDefinitely(t *testing.T) {
+	events := [][]string{[]string{"P", "x=1", "P", "x=0"}, []string{"P", "y=1", "P", "y=0"}}
+	predicate := runPredicate(t, events, "P0.x + P1.y >= 1")
+	if path := Definitely(predicate); path != nil {
+		t.Errorf("Expected the predicate to definitely hold, but it never held along %v", path)
+	}
+}
+
+// Tests that a mutual exclusion violation is not possible when the critical sections are ordered by a message.
+func TestPossiblyMutualExclusion(t *testing.T) {
+	p0Events := []string{"P", "cs=1", "P", "cs=0", "S1"}
+	p1Events := []string{"R0", "P", "cs=1", "P", "cs=0"}
+	predicate := runPredicate(t, [][]string{p0Events, p1Events}, "P0.cs == 1 && P1.cs == 1")
+	if cut := Possibly(predicate); cut != nil {
+		t.Errorf("Expected both processes never to be in the critical section, but they were at %s", formatCut(cut))
+	}
+
+	// Without the message, both critical sections can overlap.
+	p0Events = []string{"P", "cs=1", "P", "cs=0"}
+	p1Events = []string{"P", "cs=1", "P", "cs=0"}
+	predicate = runPredicate(t, [][]string{p0Events, p1Events}, "P0.cs == 1 && P1.cs == 1")
+	if cut := Possibly(predicate); cut == nil {
+		t.Errorf("Expected both processes to possibly be in the critical section, but they were not.")
+	}
+}
+
+// Tests that predicates can refer to processes by name as well as by number.
+func TestPredicateNames(t *testing.T) {
+	events := [][]string{{"P", "x=1", "S1"}, {"R0", "P", "y=2"}}
+	if err := RunVectorClock(events, WithProcessNames([]string{"alice", "bob"})); err != nil {
+		t.Fatal(err)
+	}
+	predicate, err := parsePredicate("alice.x + P1.y == 3", len(allClockValues), processNumbers(runConfig.names))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cut := Possibly(predicate); cut == nil || cut[1] != 2 {
+		t.Errorf("Expected the predicate to possibly hold once bob has set y, but got %v", cut)
+	}
+	for _, text := range []string{"carol.x == 1", ".x == 1", "alice == 1"} {
+		if _, err := parsePredicate(text, len(allClockValues), processNumbers(runConfig.names)); err == nil {
+			t.Errorf("Expected predicate %q to be rejected, but it was not.", text)
+		}
+	}
+}
+
+// Tests that invalid predicates and assignments are rejected.
+func TestInvalidPredicates(t *testing.T) {
+	if err := RunVectorClock([][]string{[]string{"P"}, []string{"P"}}); err != nil {
+		t.Fatal(err)
+	}
+	for _, text := range []string{"P0.x >", "P2.x == 1", "(P0.x == 1", "P0.x = 1", "P0 == 1", "P0.x == 1 1"} {
+		if _, err := parsePredicate(text, len(allClockValues), nil); err == nil {
+			t.Errorf("Expected predicate %q to be rejected, but it was not.", text)
+		}
+	}
+	for _, events := range [][]string{[]string{"S1", "x=1"}, []string{"x=1"}, []string{"P", "1x=1"}, []string{"P", "x=a"}} {
+		if err := RunVectorClock([][]string{events, []string{"R0"}}); err == nil {
+			t.Errorf("Expected events %v to be rejected, but they were not.", events)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/kulvirs/Concurrency-A2/vector-clocks/vclock"
+)
+
+// Returns the index of the first event in process id's timeline at which every process is known to have heard of
+// the event at index sent, meaning any message sent by that event can be garbage-collected. Returns -1 if that
+// never happens during the run.
+func collectableAt(id int, sent int) int {
+	counter := allClockValues[id][sent][id]
+	for k := sent; k < len(allMatrixValues[id]); k++ {
+		if allMatrixValues[id][k].MinKnown()[id] >= counter {
+			return k
+		}
+	}
+	return -1
+}
+
+// Prints the matrix clock values for each process, one matrix per event, along with the globally known minimum.
+// Each process's timeline ends with the point at which each of its messages could be garbage-collected.
+func printMatrixValues() {
+	for i, matrixValues := range allMatrixValues {
+		fmt.Printf("%s timeline:\n", processTitle(i))
+		for j, matrix := range matrixValues {
+			e := allTimelineEvents[i][j]
+			fmt.Printf("  %v (%s)\n", EventID{i, j}, describeEvent(e))
+			for _, row := range matrix.Rows {
+				fmt.Printf("    %s\n", vclock.FormatCounters(row))
+			}
+			fmt.Printf("    globally known minimum: %s\n", vclock.FormatCounters(matrix.MinKnown()))
+		}
+		for j, e := range allTimelineEvents[i] {
+			if e.Type != 'S' && e.Type != 'B' {
+				continue
+			}
+			if k := collectableAt(i, j); k >= 0 {
+				fmt.Printf("  message sent at %v can be garbage-collected from %v\n", EventID{i, j}, EventID{i, k})
+			} else {
+				fmt.Printf("  message sent at %v cannot be garbage-collected by the end of the run\n", EventID{i, j})
+			}
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/kulvirs/Concurrency-A2/vector-clocks/vclock"
+)
+
+// Tests that matrix clocks are recorded for every event and that the rows match the vector clocks.
+func TestMatrixClocks(t *testing.T) {
+	p0Events := []string{"S1", "P0", "R1"}
+	p1Events := []string{"R0", "S0"}
+	events := [][]string{p0Events, p1Events}
+
+	if err := RunVectorClock(events, WithMatrixClocks()); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, matrixValues := range allMatrixValues {
+		if len(matrixValues) != len(allClockValues[i]) {
+			t.Fatalf("Expected a matrix clock for each of process %d's events, but got %v", i, matrixValues)
+		}
+		for j, matrix := range matrixValues {
+			if matrix.Vector().String() != vclock.FormatCounters(allClockValues[i][j]) {
+				t.Errorf("Expected P%d.e%d's own row to be its vector clock %v, but got %v", i, j, allClockValues[i][j], matrix)
+			}
+		}
+	}
+	if allMatrixValues[0][2].String() != "[[3 2] [1 2]]" {
+		t.Errorf("Expected P0.e2 to be [[3 2] [1 2]], but got %v", allMatrixValues[0][2])
+	}
+}
+
+// Tests that a message can only be garbage-collected once its sender knows every process has heard of it.
+func TestCollectableAt(t *testing.T) {
+	// Process 0 only learns process 1 has seen its message when process 1 replies.
+	p0Events := []string{"S1", "P0", "R1", "S1"}
+	p1Events := []string{"R0", "S0", "R0"}
+	events := [][]string{p0Events, p1Events}
+
+	if err := RunVectorClock(events, WithMatrixClocks()); err != nil {
+		t.Fatal(err)
+	}
+
+	if k := collectableAt(0, 0); k != 2 {
+		t.Errorf("Expected P0.e0's message to be collectable from P0.e2, but got %d", k)
+	}
+	if k := collectableAt(0, 3); k != -1 {
+		t.Errorf("Expected P0.e3's message to never be collectable, but got %d", k)
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/kulvirs/Concurrency-A2/vector-clocks/vclock"
+)
+
+// Shared variables, locks and data race detection. Reads (Rx) and writes (Wx) of shared variables are local
+// events, and locks (L and U) order the processes that use them: acquiring a lock merges the clocks of the
+// process that last released it, like receiving a message. Two accesses of the same variable race if at least
+// one is a write and neither happens before the other.
+
+// State of a lock shared by the processes.
+type simLock struct {
+	// The process holding the lock, or -1 if it is free.
+	holder int
+	// Stamped with the clocks of the process that last released the lock, nil if it has never been released.
+	released *Message
+	// The unlock event that last released the lock.
+	releasedBy EventID
+}
+
+// Stores the locks used in the current run, by name. Only accessed while holding the deadlock monitor's mutex.
+var locks map[string]*simLock
+
+// Records every time a lock was taken after being released, as a pair of the unlock event and the lock event
+// that took it next, in the order the locks were taken. Only accessed while holding the deadlock monitor's mutex.
+var allLockHandoffs [][2]EventID
+
+// Access is a read or write of a shared variable.
+type Access struct {
+	// The event that made the access.
+	ID EventID
+	// The read or write event.
+	Event Event
+	// The vector clock of the event.
+	Clock []int
+}
+
+// Race is a pair of accesses to the same variable, at least one of them a write, that are not ordered by
+// happens-before.
+type Race struct {
+	// The variable both events access.
+	Variable string
+	// The access that happened first in the run.
+	First Access
+	// The access that was found to race with the first.
+	Second Access
+}
+
+// Records the data races found in the current run.
+var allRaces []Race
+
+// Detects data races as the accesses happen, in the style of FastTrack. For each variable it keeps the last
+// write and the reads since then that are not known to happen before a later read, so the reads are a single
+// epoch while they are ordered and only grow into a vector when processes read the variable concurrently.
+// An access happens before the current event of process t if its own entry in its clock is at most the entry
+// in t's clock.
+type raceDetector struct {
+	mutex sync.Mutex
+	// The last write of each variable.
+	writes map[string]*Access
+	// The reads of each variable since its last write that are not ordered before another read.
+	reads map[string][]*Access
+	// The races found so far.
+	races []Race
+}
+
+// Stores the race detector for the current run.
+var detector *raceDetector
+
+// Creates a race detector with no accesses.
+func newRaceDetector() *raceDetector {
+	return &raceDetector{writes: map[string]*Access{}, reads: map[string][]*Access{}, races: []Race{}}
+}
+
+// Returns true if the access happens before the event with the given vector clock.
+func happensBefore(a *Access, clock []int) bool {
+	p := a.ID.Process
+	return a.Clock[p] <= clock[p]
+}
+
+// Checks a read or write of a shared variable against the earlier accesses, recording any races, and then
+// records the access.
+func (d *raceDetector) access(a Access) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	x := a.Event.Variable
+	if w := d.writes[x]; w != nil && !happensBefore(w, a.Clock) {
+		d.races = append(d.races, Race{x, *w, a})
+	}
+	if a.Event.Type == 'r' {
+		// Reads ordered before this one are covered by it.
+		reads := []*Access{}
+		for _, r := range d.reads[x] {
+			if !happensBefore(r, a.Clock) {
+				reads = append(reads, r)
+			}
+		}
+		d.reads[x] = append(reads, &a)
+		return
+	}
+	for _, r := range d.reads[x] {
+		if !happensBefore(r, a.Clock) {
+			d.races = append(d.races, Race{x, *r, a})
+		}
+	}
+	d.writes[x] = &a
+	d.reads[x] = nil
+}
+
+// Records a read or write of a shared variable as a local event and passes it to the race detector.
+func (state *nodeState) accessVariable(e Event) {
+	id := state.clock.ID
+	state.tick()
+	state.record(e)
+	detector.access(Access{EventID{id, len(state.clockValues) - 1}, e, state.clock.Copy().Counters})
+}
+
+// Acquires a lock, waiting until it is free, and merges the clocks of the process that last released it.
+// Returns false if the simulation deadlocked before the lock was free.
+func (state *nodeState) acquire(e Event, index int) bool {
+	id := state.clock.ID
+	lock := locks[e.Variable]
+	var released *Message
+	acquired := deadlockMonitor.do(id, index, id, []string{lockName(e.Variable)}, func() bool {
+		return lock.holder < 0
+	}, func() bool {
+		if lock.holder >= 0 {
+			return false
+		}
+		lock.holder = id
+		released = lock.released
+		if released != nil {
+			allLockHandoffs = append(allLockHandoffs, [2]EventID{lock.releasedBy, {id, len(state.clockValues)}})
+		}
+		return true
+	})
+	if !acquired {
+		return false
+	}
+	if released != nil {
+		state.merge(*released)
+	} else {
+		state.tick()
+	}
+	state.record(e)
+	return true
+}
+
+// Releases a lock, leaving the node's clocks for the next process to acquire it.
+func (state *nodeState) release(e Event, index int) {
+	id := state.clock.ID
+	lock := locks[e.Variable]
+	state.tick()
+	msg := state.message()
+	unlock := EventID{id, len(state.clockValues)}
+	deadlockMonitor.do(id, index, id, []string{lockName(e.Variable)}, nil, func() bool {
+		lock.holder = -1
+		lock.released = &msg
+		lock.releasedBy = unlock
+		return true
+	})
+	state.record(e)
+}
+
+// Returns a free lock for every lock used by the events.
+func newLocks(events [][]Event) map[string]*simLock {
+	locks := map[string]*simLock{}
+	for _, processEvents := range events {
+		for _, e := range processEvents {
+			if e.Type == 'L' {
+				locks[e.Variable] = &simLock{holder: -1}
+			}
+		}
+	}
+	return locks
+}
+
+// Returns the name of a lock as it is printed, "lock" for the default lock.
+func lockName(name string) string {
+	if name == "" {
+		return "lock"
+	}
+	return "lock " + name
+}
+
+// Returns a label describing a shared variable access or lock event in a timeline, or an empty string for any other event.
+func accessLabel(e Event) string {
+	switch e.Type {
+	case 'r':
+		return "read " + e.Variable
+	case 'w':
+		return "write " + e.Variable
+	case 'L':
+		return "acquire " + lockName(e.Variable)
+	case 'U':
+		return "release " + lockName(e.Variable)
+	}
+	return ""
+}
+
+// Prints every data race found in the run, with the events, processes and vector clocks of both accesses.
+// Nothing is printed if the run does not access any shared variables.
+func printRaces() {
+	accesses := false
+	for _, events := range allTimelineEvents {
+		for _, e := range events {
+			accesses = accesses || e.Type == 'r' || e.Type == 'w'
+		}
+	}
+	if !accesses {
+		return
+	}
+	if len(allRaces) == 0 {
+		fmt.Printf("No data races detected.\n")
+		return
+	}
+	fmt.Printf("Data races detected:\n")
+	for _, race := range allRaces {
+		fmt.Printf("  %s: %v (%v) at process %d with clock %s || %v (%v) at process %d with clock %s\n", race.Variable,
+			race.First.ID, race.First.Event, race.First.ID.Process, vclock.FormatCounters(race.First.Clock),
+			race.Second.ID, race.Second.Event, race.Second.ID.Process, vclock.FormatCounters(race.Second.Clock))
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kulvirs/Concurrency-A2/sched"
+)
+
+// Tests that unordered writes and reads of the same variable are reported with both events and their clocks.
+func TestRaceUnordered(t *testing.T) {
+	p0Events := []string{"Wx", "P"}
+	p1Events := []string{"P", "Rx"}
+	events := [][]string{p0Events, p1Events}
+
+	if err := RunVectorClock(events); err != nil {
+		t.Fatal(err)
+	}
+	if len(allRaces) != 1 {
+		t.Fatalf("Expected 1 race, but got %v", allRaces)
+	}
+	race := allRaces[0]
+	accesses := map[EventID]Access{race.First.ID: race.First, race.Second.ID: race.Second}
+	write, read := accesses[EventID{0, 0}], accesses[EventID{1, 1}]
+	if race.Variable != "x" || write.Event.Type != 'w' || read.Event.Type != 'r' {
+		t.Errorf("Expected the write at P0.e0 to race with the read at P1.e1, but got %+v", race)
+	}
+	if write.Clock[0] != 1 || write.Clock[1] != 0 || read.Clock[0] != 0 || read.Clock[1] != 2 {
+		t.Errorf("Expected the clocks [1 0] and [0 2], but got %v and %v", write.Clock, read.Clock)
+	}
+}
+
+// Tests that accesses ordered by a message or by a lock do not race.
+func TestRaceOrdered(t *testing.T) {
+	// The write at process 0 happens before process 1 receives its message.
+	p0Events := []string{"Wx", "S1"}
+	p1Events := []string{"R0", "Wx", "Rx"}
+	if err := RunVectorClock([][]string{p0Events, p1Events}); err != nil {
+		t.Fatal(err)
+	}
+	if len(allRaces) != 0 {
+		t.Errorf("Expected no races with a message, but got %v", allRaces)
+	}
+
+	// Both accesses are made while holding the lock, so whichever process acquires it second sees the other's write.
+	for run := 0; run < 20; run++ {
+		p0Events = []string{"L", "Wx", "U"}
+		p1Events = []string{"L", "Wx", "Rx", "U"}
+		if err := RunVectorClock([][]string{p0Events, p1Events}); err != nil {
+			t.Fatal(err)
+		}
+		if len(allRaces) != 0 {
+			t.Errorf("Expected no races with a lock, but got %v", allRaces)
+		}
+	}
+}
+
+// Tests that concurrent reads do not race with each other, but both race with a later unordered write.
+func TestRaceConcurrentReads(t *testing.T) {
+	p0Events := []string{"Rx", "S2"}
+	p1Events := []string{"Rx"}
+	p2Events := []string{"R0", "Wx"}
+	if err := RunVectorClock([][]string{p0Events, p1Events, p2Events}); err != nil {
+		t.Fatal(err)
+	}
+	// The read at process 0 happens before the write, but the read at process 1 does not. Depending on the
+	// order of the accesses, the race is found when the write or the read at process 1 happens.
+	if len(allRaces) != 1 {
+		t.Fatalf("Expected 1 race, but got %v", allRaces)
+	}
+	race := allRaces[0]
+	if race.First.ID.Process+race.Second.ID.Process != 3 {
+		t.Errorf("Expected the read at process 1 to race with the write at process 2, but got %+v", race)
+	}
+}
+
+// Tests that processes waiting for each other's locks are reported as deadlocked.
+func TestLockDeadlock(t *testing.T) {
+	for run := 0; run < 20; run++ {
+		p0Events := []string{"La", "S1", "R1", "Lb", "Ub", "Ua"}
+		p1Events := []string{"Lb", "R0", "S0", "La", "Ua", "Ub"}
+		err := RunVectorClock([][]string{p0Events, p1Events})
+		if err == nil || !strings.Contains(err.Error(), "waiting for lock b held by process 1") {
+			t.Errorf("Expected process 0 to be waiting for lock b, but got %v", err)
+		}
+	}
+
+	// The holder is reported when the scheduler never lets process 0 attempt to take the lock.
+	for seed := int64(0); seed < 10; seed++ {
+		p0Events := []string{"La", "S1", "R1", "Lb", "Ub", "Ua"}
+		p1Events := []string{"Lb", "R0", "S0", "La", "Ua", "Ub"}
+		err := RunVectorClock([][]string{p0Events, p1Events}, WithScheduler(sched.New(seed)))
+		if err == nil || !strings.Contains(err.Error(), "waiting for lock b held by process 1") {
+			t.Errorf("Expected process 0 to be waiting for lock b with seed %d, but got %v", seed, err)
+		}
+	}
+}
+
+// Tests that locks must be released by the process holding them.
+func TestValidateLocks(t *testing.T) {
+	for _, events := range [][]string{[]string{"L", "L", "U", "U"}, []string{"U"}, []string{"Lm"}, []string{"Lm", "U"}} {
+		if err := RunVectorClock([][]string{events, []string{"P"}}); err == nil {
+			t.Errorf("Expected events %v to be rejected, but they were not.", events)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Reading scripts of events. Each line holds the events of one process, separated by spaces, and can start with a
+// name for the process, such as "alice:". Events can refer to a named process with @ instead of its number, such
+// as S@bob, and can end in a quoted label, such as S1:"order#42". Everything after a # outside a label is a
+// comment, blank lines are skipped, and lines containing -> are happens-before queries, which come after the
+// processes.
+
+// Script is a script of events read from the input.
+type Script struct {
+	// The commands of each process, with named processes replaced by their numbers.
+	Commands [][]string
+	// The name of each process, empty for processes without a name.
+	Names []string
+	// The happens-before queries after the processes.
+	Queries []string
+}
+
+// ScriptError is an error in a script, at the given line and column, both counted from 1.
+type ScriptError struct {
+	Line   int
+	Column int
+	Err    error
+}
+
+func (err *ScriptError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %v", err.Line, err.Column, err.Err)
+}
+
+// A token read from a line of a script, along with the column it starts at.
+type scriptToken struct {
+	Text   string
+	Column int
+}
+
+// Splits a line into tokens separated by white space, stopping at a comment. A quoted label is part of the token
+// it is in, even if it contains spaces or a #.
+func tokenizeLine(line string) ([]scriptToken, error) {
+	tokens := []scriptToken{}
+	start := -1
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		if c == '"' {
+			// Skip to the closing quote, past any escaped characters.
+			quote := i
+			for i++; i < len(line) && line[i] != '"'; i++ {
+				if line[i] == '\\' {
+					i++
+				}
+			}
+			if i >= len(line) {
+				return nil, &ScriptError{0, quote + 1, errors.New("unterminated label")}
+			}
+			if start < 0 {
+				start = quote
+			}
+			continue
+		}
+		if c == '#' || c == ' ' || c == '\t' || c == '\r' {
+			if start >= 0 {
+				tokens = append(tokens, scriptToken{line[start:i], start + 1})
+				start = -1
+			}
+			if c == '#' {
+				return tokens, nil
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, scriptToken{line[start:], start + 1})
+	}
+	return tokens, nil
+}
+
+// Returns true if a line of tokens is a happens-before query.
+func isQuery(tokens []scriptToken) bool {
+	for _, token := range tokens {
+		if strings.Contains(token.Text, "->") && !strings.Contains(token.Text, "\"") {
+			return true
+		}
+	}
+	return false
+}
+
+// Replaces a reference to a named process, such as S@bob:"hi", with the process's number, such as S1:"hi".
+// Returns an error if the name is not the name of a process.
+func resolveName(command string, numbers map[string]int) (string, error) {
+	at := strings.Index(command, "@")
+	if at < 0 || (strings.Contains(command, "\"") && at > strings.Index(command, "\"")) {
+		return command, nil
+	}
+	end := len(command)
+	if colon := strings.Index(command, ":\""); colon > at {
+		end = colon
+	}
+	name := command[at+1 : end]
+	number, ok := numbers[name]
+	if !ok {
+		return "", fmt.Errorf("unknown process %q", name)
+	}
+	return fmt.Sprintf("%s%d%s", command[:at], number, command[end:]), nil
+}
+
+// Returns the number of each named process, given the name of every process.
+func processNumbers(names []string) map[string]int {
+	numbers := map[string]int{}
+	for i, name := range names {
+		if name != "" {
+			numbers[name] = i
+		}
+	}
+	return numbers
+}
+
+// Replaces the names of processes in the event labels of a query, such as bob.e2, with their numbers, such as P1.e2.
+func resolveQuery(query string, numbers map[string]int) string {
+	sides := strings.Split(query, "->")
+	for i, side := range sides {
+		label := strings.TrimSpace(side)
+		for name, number := range numbers {
+			if strings.HasPrefix(label, name+".") {
+				sides[i] = strings.Replace(side, name+".", fmt.Sprintf("P%d.", number), 1)
+			}
+		}
+	}
+	return strings.Join(sides, "->")
+}
+
+// Reads a script from r, returning it along with the number of processes it runs. Returns a ScriptError for the
+// first problem found, including invalid events. If id is not negative, the script is the one line of events of
+// process id running on its own, and its peers can be any of the given number of processes.
+func parseScript(r io.Reader, id int, processes int) (*Script, int, error) {
+	script := &Script{Commands: [][]string{}, Names: []string{}, Queries: []string{}}
+	// The tokens of each process and the line they are on.
+	tokens := [][]scriptToken{}
+	lines := []int{}
+	numbers := map[string]int{}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		lineTokens, err := tokenizeLine(scanner.Text())
+		if err != nil {
+			err.(*ScriptError).Line = line
+			return nil, 0, err
+		}
+		if len(lineTokens) == 0 {
+			continue
+		}
+		if isQuery(lineTokens) {
+			query := strings.TrimSpace(strings.SplitN(scanner.Text(), "#", 2)[0])
+			script.Queries = append(script.Queries, resolveQuery(query, numbers))
+			continue
+		}
+		if len(script.Queries) > 0 {
+			return nil, 0, &ScriptError{line, lineTokens[0].Column, errors.New("processes must come before the queries")}
+		}
+		name := ""
+		if first := lineTokens[0].Text; strings.HasSuffix(first, ":") {
+			name = strings.TrimSuffix(first, ":")
+			if !validVariable(name) {
+				return nil, 0, &ScriptError{line, lineTokens[0].Column, fmt.Errorf("invalid process name %q", name)}
+			}
+			if _, ok := numbers[name]; ok {
+				return nil, 0, &ScriptError{line, lineTokens[0].Column, fmt.Errorf("process %q is named more than once", name)}
+			}
+			numbers[name] = len(tokens)
+			lineTokens = lineTokens[1:]
+		}
+		script.Names = append(script.Names, name)
+		tokens = append(tokens, lineTokens)
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	for i, processTokens := range tokens {
+		commands := []string{}
+		for _, token := range processTokens {
+			command, err := resolveName(token.Text, numbers)
+			if err != nil {
+				return nil, 0, &ScriptError{lines[i], token.Column, err}
+			}
+			commands = append(commands, command)
+		}
+		script.Commands = append(script.Commands, commands)
+	}
+	// Check the events now, so an invalid one can be reported with its position. parseEvents checks every peer
+	// is one of the processes.
+	commands := script.Commands
+	if id >= 0 {
+		if len(commands) != 1 {
+			return nil, 0, errors.New("a process running over TCP should be given one line of events")
+		}
+		if id >= processes {
+			return nil, 0, fmt.Errorf("process %d has no address", id)
+		}
+		commands = make([][]string, processes)
+		commands[id] = script.Commands[0]
+	}
+	if _, err := parseEvents(commands); err != nil {
+		var eventErr *EventError
+		if errors.As(err, &eventErr) {
+			line := eventErr.Process
+			if id >= 0 {
+				line = 0
+			}
+			return nil, 0, &ScriptError{lines[line], tokens[line][eventErr.Command].Column, eventErr.Err}
+		}
+		return nil, 0, err
+	}
+	return script, len(script.Commands), nil
+}
+
+// Returns a label showing an event's own label and the payload it received, or an empty string if it has neither.
+func payloadLabel(e Event) string {
+	labels := []string{}
+	if e.Label != "" {
+		labels = append(labels, strconv.Quote(e.Label))
+	}
+	if e.Payload != "" {
+		labels = append(labels, "received "+strconv.Quote(e.Payload))
+	}
+	return strings.Join(labels, ", ")
+}
+
+// Returns every label describing an event in a timeline, separated by commas, or an empty string if it has none.
+func eventLabel(e Event) string {
+	labels := []string{}
+	for _, label := range []string{anyLabel(e), broadcastLabel(e), forkLabel(e), accessLabel(e), payloadLabel(e), timeoutLabel(e)} {
+		if label != "" {
+			labels = append(labels, label)
+		}
+	}
+	return strings.Join(labels, ", ")
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Tests that a script in the terse format, with queries after a blank line, is read as before.
+func TestParseScriptTerse(t *testing.T) {
+	defer func(n int) { numProcesses = n }(numProcesses)
+	numProcesses = 0
+	script, processes, err := parseScript(strings.NewReader("S1 R1 P0 R1\nS0 R0 P1 S2 S0 P1 R2\nP2 S1 R1 P2\n\nP0.e0 -> P1.e1?\n"), -1, 0)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	if processes != 3 || numProcesses == 3 {
+		t.Errorf("Expected 3 processes, without the simulation being sized for them, but got %d", processes)
+	}
+	expected := [][]string{{"S1", "R1", "P0", "R1"}, {"S0", "R0", "P1", "S2", "S0", "P1", "R2"}, {"P2", "S1", "R1", "P2"}}
+	if !reflect.DeepEqual(script.Commands, expected) {
+		t.Errorf("Expected commands %v but got %v", expected, script.Commands)
+	}
+	if !reflect.DeepEqual(script.Queries, []string{"P0.e0 -> P1.e1?"}) {
+		t.Errorf("Expected one query but got %v", script.Queries)
+	}
+	if !reflect.DeepEqual(script.Names, []string{"", "", ""}) {
+		t.Errorf("Expected no names but got %v", script.Names)
+	}
+}
+
+// Tests that names, labels, comments and blank lines are read, with names replaced by process numbers.
+func TestParseScriptExtended(t *testing.T) {
+	text := `# An order being placed.
+
+shop: S@warehouse:"order #42" R@warehouse # wait for the shipment
+warehouse:   R@shop   S@shop:"say \"hi\""
+P:"audit" x=1
+
+shop.e0 -> warehouse.e1? # a query
+`
+	script, _, err := parseScript(strings.NewReader(text), -1, 0)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	expected := [][]string{{`S1:"order #42"`, "R1"}, {"R0", `S0:"say \"hi\""`}, {`P:"audit"`, "x=1"}}
+	if !reflect.DeepEqual(script.Commands, expected) {
+		t.Errorf("Expected commands %v but got %v", expected, script.Commands)
+	}
+	if !reflect.DeepEqual(script.Names, []string{"shop", "warehouse", ""}) {
+		t.Errorf("Expected names shop and warehouse but got %v", script.Names)
+	}
+	if !reflect.DeepEqual(script.Queries, []string{"P0.e0 -> P1.e1?"}) {
+		t.Errorf("Expected the query to use process numbers but got %v", script.Queries)
+	}
+}
+
+// Tests that every error in a script is reported with its line and column.
+func TestParseScriptErrors(t *testing.T) {
+	tests := map[string]string{
+		"S1 R1\nR0 S9":               "line 2, column 4: invalid command: S9",
+		"a: S@b\nb: R@c":             `line 2, column 4: unknown process "c"`,
+		"P\n  S1:\"open":             "line 2, column 6: unterminated label",
+		"a: P\na: P":                 `line 2, column 1: process "a" is named more than once`,
+		"1a: P":                      `line 1, column 1: invalid process name "1a"`,
+		"S1\nR0\nP0.e0 -> P1.e0?\nP": "line 4, column 1: processes must come before the queries",
+		"P\n# comment\n\tx=1 P":      "line 3, column 2: assignment x=1 must follow a local event (P)",
+		"P S1:hi\nP":                 "line 1, column 3: invalid command: S1:hi",
+	}
+	for text, expected := range tests {
+		_, _, err := parseScript(strings.NewReader(text), -1, 0)
+		if err == nil || err.Error() != expected {
+			t.Errorf("Expected %q to fail with %q but got %v", text, expected, err)
+		}
+	}
+}
+
+// Tests that labels are carried to the receiver as payloads, and that events print with their labels.
+func TestPayloads(t *testing.T) {
+	err := RunVectorClock([][]string{{`S1:"order #42"`, "R1", `B:"all"`}, {"R0", "S0", "R0"}})
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	send := allTimelineEvents[0][0]
+	if send.Label != "order #42" || send.String() != `S1:"order #42"` {
+		t.Errorf("Expected the send to be labelled, but got %v", send)
+	}
+	if receive := allTimelineEvents[1][0]; receive.Payload != "order #42" || eventLabel(receive) != `received "order #42"` {
+		t.Errorf("Expected the receive to show the payload, but got %q", eventLabel(receive))
+	}
+	if receive := allTimelineEvents[0][1]; receive.Payload != "" || eventLabel(receive) != "" {
+		t.Errorf("Expected an unlabelled message to carry no payload, but got %q", eventLabel(receive))
+	}
+	if receive := allTimelineEvents[1][2]; receive.Payload != "all" {
+		t.Errorf("Expected the broadcast to carry its label, but got %v", receive)
+	}
+	if e := (Event{Type: 'P', Peer: 0, Label: "x", Assign: "y=1"}); e.String() != `P:"x" y=1` {
+		t.Errorf("Expected P:\"x\" y=1 but got %v", e)
+	}
+}
+
+// Tests that named processes are shown in the timelines.
+func TestProcessNames(t *testing.T) {
+	if err := RunVectorClock([][]string{{"P"}, {"P"}}, WithProcessNames([]string{"alice", ""})); err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	if processTitle(0) != "Process 0 (alice)" || processTitle(1) != "Process 1" {
+		t.Errorf("Expected only process 0 to be named, but got %q and %q", processTitle(0), processTitle(1))
+	}
+}
+
+// Tests that queries given outside the script can use the names of the processes in the run.
+func TestResolveQueryNames(t *testing.T) {
+	if err := RunVectorClock([][]string{{"S1"}, {"R0"}}, WithProcessNames([]string{"alice", "bob"})); err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	query := resolveQuery("alice.e0 -> bob.e0?", processNumbers(runConfig.names))
+	if answer, err := answerQuery(query); err != nil || answer != "P0.e0 -> P1.e0? yes" {
+		t.Errorf("Expected alice.e0 to happen before bob.e0, but got %q (%v)", answer, err)
+	}
+}
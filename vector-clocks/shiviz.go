@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// The regular expression ShiViz uses to parse the log written by writeShiViz.
+const shiVizRegex = `(?<host>\S*) (?<clock>{.*})\n(?<event>.*)`
+
+// Returns the host name used for a process in ShiViz logs.
+func hostName(id int) string {
+	return fmt.Sprintf("P%d", id)
+}
+
+// Describes an event for the event line of a ShiViz log.
+func describeEvent(e Event) string {
+	if label := payloadLabel(e); label != "" {
+		e.Label, e.Payload = "", ""
+		return describeEvent(e) + " (" + label + ")"
+	}
+	switch e.Type {
+	case 'S':
+		return "send to " + hostName(e.Peer)
+	case 'R':
+		if e.TimedOut && e.Any {
+			return "receive from any process timed out"
+		}
+		if e.TimedOut {
+			return "receive from " + hostName(e.Peer) + " timed out"
+		}
+		if e.Broadcast {
+			return "receive broadcast from " + hostName(e.Peer)
+		}
+		return "receive from " + hostName(e.Peer)
+	case 'B':
+		return "broadcast"
+	case 'D':
+		return "deliver broadcast from " + hostName(e.Peer)
+	case 'F':
+		return "fork " + hostName(e.Peer)
+	case 'J':
+		return "join " + hostName(e.Peer)
+	case 'r', 'w', 'L', 'U':
+		return accessLabel(e)
+	}
+	return "local event"
+}
+
+// Writes the recorded timelines as a ShiViz (GoVector style) log. The first line is the regular expression
+// for ShiViz to parse the log with, followed by a blank line, then two lines for every event: the host name
+// and its vector clock as a JSON object, then a description of the event.
+func writeShiViz(w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "%s\n\n", shiVizRegex); err != nil {
+		return err
+	}
+	for i, clockValues := range allClockValues {
+		for j, clockValue := range clockValues {
+			// Like GoVector, only the hosts the process has heard from are included in the clock.
+			clock := map[string]int{}
+			for k, counter := range clockValue {
+				if counter > 0 {
+					clock[hostName(k)] = counter
+				}
+			}
+			encoded, err := json.Marshal(clock)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(w, "%s %s\n%s\n", hostName(i), encoded, describeEvent(allTimelineEvents[i][j])); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
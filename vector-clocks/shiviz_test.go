@@ -0,0 +1,29 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+// Tests that a run is written as a ShiViz log with the regular expression header and two lines per event.
+func TestWriteShiViz(t *testing.T) {
+	p0Events := []string{"S1", "P0"}
+	p1Events := []string{"R0"}
+	events := [][]string{p0Events, p1Events}
+
+	if err := RunVectorClock(events); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := writeShiViz(&out); err != nil {
+		t.Fatal(err)
+	}
+	expected := shiVizRegex + "\n\n" +
+		"P0 {\"P0\":1}\nsend to P1\n" +
+		"P0 {\"P0\":2}\nlocal event\n" +
+		"P1 {\"P0\":1,\"P1\":1}\nreceive from P0\n"
+	if out.String() != expected {
+		t.Errorf("Expected the log:\n%s\nbut got:\n%s", expected, out.String())
+	}
+}
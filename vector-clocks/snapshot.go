@@ -0,0 +1,249 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/kulvirs/Concurrency-A2/vector-clocks/vclock"
+)
+
+// Consistent global snapshots with the Chandy-Lamport marker algorithm. A process that initiates a snapshot
+// records its state and sends a marker on every outgoing channel. A process that receives its first marker
+// of a snapshot does the same, and every process records the messages that arrive on each incoming channel
+// after it recorded its state and before the marker on that channel. Markers are control messages, so they
+// do not change any clocks and are not part of the timelines.
+
+// Snapshot is the global state recorded by one run of the marker algorithm.
+type Snapshot struct {
+	// The process that initiated the snapshot.
+	Initiator int
+	// The index of the C event in the initiator's list of events.
+	Event int
+	// The number of events in each process's timeline that are inside the cut, or -1 if the process never
+	// recorded its state.
+	Cut []int
+	// The vector clock recorded by each process.
+	Clocks [][]int
+	// InFlight[i][j] holds the messages recorded as in flight on the channel from process i to process j.
+	InFlight [][][]Message
+}
+
+// Records the snapshots taken during the current run, one for each C event.
+var allSnapshots []Snapshot
+
+// State kept by a node for one snapshot.
+type localSnapshot struct {
+	// Whether the node has recorded its state.
+	recorded bool
+	// Whether the marker from each process has been received.
+	markers []bool
+}
+
+// Returns an empty snapshot for every C event, in process order.
+func newSnapshots(events [][]Event) []Snapshot {
+	snapshots := []Snapshot{}
+	for i, processEvents := range events {
+		for j, e := range processEvents {
+			if e.Type != 'C' {
+				continue
+			}
+			s := Snapshot{i, j, make([]int, len(events)), make([][]int, len(events)), make([][][]Message, len(events))}
+			for k := range events {
+				s.Cut[k] = -1
+				s.InFlight[k] = make([][]Message, len(events))
+			}
+			snapshots = append(snapshots, s)
+		}
+	}
+	return snapshots
+}
+
+// Initiates the snapshot for the C event at the given index. Returns false if the simulation deadlocked
+// before a marker could be sent.
+func (state *nodeState) initiateSnapshot(index int) bool {
+	id := state.clock.ID
+	for s, snapshot := range allSnapshots {
+		if snapshot.Initiator == id && snapshot.Event == index {
+			return state.recordSnapshot(s, index)
+		}
+	}
+	return true
+}
+
+// Records the node's state for snapshot s and sends a marker to every other process.
+// Returns false if the simulation deadlocked before a marker could be sent.
+func (state *nodeState) recordSnapshot(s int, index int) bool {
+	id := state.clock.ID
+	local := state.snapshots[s]
+	local.recorded = true
+	local.markers[id] = true
+	allSnapshots[s].Cut[id] = len(state.clockValues)
+	allSnapshots[s].Clocks[id] = state.clock.Copy().Counters
+	for dest := 0; dest < numProcesses; dest++ {
+		if dest != id && !send(id, dest, index, Message{Marker: true, Snapshot: s}) {
+			return false
+		}
+	}
+	return true
+}
+
+// Handles a marker received from source, recording the node's state if it is the first marker of its snapshot.
+// Returns false if the simulation deadlocked before a marker could be sent.
+func (state *nodeState) handleMarker(source int, msg Message, index int) bool {
+	if !state.snapshots[msg.Snapshot].recorded && !state.recordSnapshot(msg.Snapshot, index) {
+		return false
+	}
+	state.snapshots[msg.Snapshot].markers[source] = true
+	return true
+}
+
+// Receives the next message from source, or from any process if source is anySource, for the event at the
+// given index, handling any markers that arrive before it. The message is recorded as in flight for every
+// snapshot still recording the channel. Returns the process the message came from, or false if the simulation
+// deadlocked before a message arrived. A receive that times out gets an empty message marked as timed out.
+func (state *nodeState) receive(source int, index int) (int, Message, bool) {
+	id := state.clock.ID
+	for {
+		from, msg, ok := source, Message{}, false
+		if source == anySource {
+			from, msg, ok = transport.ReceiveAny(id, index)
+		} else {
+			msg, ok = recv(id, source, index)
+		}
+		if !ok || msg.TimedOut {
+			return from, msg, ok
+		}
+		if msg.Marker {
+			if !state.handleMarker(from, msg, index) {
+				return from, msg, false
+			}
+			continue
+		}
+		msg = state.decompress(from, msg)
+		for s, local := range state.snapshots {
+			if local.recorded && !local.markers[from] {
+				allSnapshots[s].InFlight[from][id] = append(allSnapshots[s].InFlight[from][id], msg)
+			}
+		}
+		return from, msg, true
+	}
+}
+
+// Returns true once the node has received every marker of every snapshot.
+func (state *nodeState) finishedSnapshots() bool {
+	for _, local := range state.snapshots {
+		for _, received := range local.markers {
+			if !received {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Receives markers after the node's last event until it has finished every snapshot. Every message sent to the
+// node has been received by then, so only markers are left on its channels.
+// Returns false if the simulation deadlocked before every marker arrived.
+func (state *nodeState) finishSnapshots(index int) bool {
+	id := state.clock.ID
+	for !state.finishedSnapshots() {
+		from, msg, received := transport.ReceiveAny(id, index)
+		if !received {
+			return false
+		}
+		// Faults can leave messages behind that were never received, such as duplicates.
+		if msg.Marker && !state.handleMarker(from, msg, index) {
+			return false
+		}
+	}
+	return true
+}
+
+// Checks that a snapshot is a consistent cut using the recorded vector clocks and timelines. A cut is consistent if
+// no process recorded a clock that knows of events of another process outside the cut, and each message recorded
+// in flight must have been sent inside the cut but not received inside it. Returns every problem found.
+func checkSnapshot(snapshot Snapshot) []string {
+	problems := []string{}
+	for i := range snapshot.Clocks {
+		if snapshot.Cut[i] < 0 {
+			problems = append(problems, fmt.Sprintf("P%d never recorded its state", i))
+		}
+	}
+	if len(problems) > 0 {
+		return problems
+	}
+	for i, clock := range snapshot.Clocks {
+		for j, other := range snapshot.Clocks {
+			if i != j && other[i] > clock[i] {
+				problems = append(problems, fmt.Sprintf("P%d recorded %s, which knows of %d event(s) of P%d, but only %d are inside the cut",
+					j, vclock.FormatCounters(other), other[i], i, clock[i]))
+			}
+		}
+	}
+	for i := range snapshot.InFlight {
+		for j, messages := range snapshot.InFlight[i] {
+			for _, msg := range messages {
+				sent := msg.Clock.Counters[i]
+				if sent > snapshot.Clocks[i][i] {
+					problems = append(problems, fmt.Sprintf("message %s in flight from P%d to P%d was sent outside the cut", vclock.FormatCounters(msg.Clock.Counters), i, j))
+				} else if receivedInCut(i, j, snapshot.Cut[j], msg) {
+					problems = append(problems, fmt.Sprintf("message %s in flight from P%d to P%d was received inside the cut", vclock.FormatCounters(msg.Clock.Counters), i, j))
+				}
+			}
+		}
+	}
+	return problems
+}
+
+// Returns true if process j received the message from process i within the first cut events of its timeline.
+// Knowing of the send is not enough, since that knowledge can reach process j through another process while the
+// message is still in flight. Messages on a channel are received in the order they were sent, unless faults
+// numbered them.
+func receivedInCut(i int, j int, cut int, msg Message) bool {
+	position := msg.Sequence
+	if position == 0 {
+		// The position of the message among the messages sent from process i to process j.
+		for k, e := range allTimelineEvents[i] {
+			if ((e.Type == 'S' && e.Peer == j) || e.Type == 'B') && allClockValues[i][k][i] <= msg.Clock.Counters[i] {
+				position++
+			}
+		}
+	}
+	received := 0
+	for _, e := range allTimelineEvents[j][:cut] {
+		if e.Type != 'R' || e.Peer != i || e.TimedOut {
+			continue
+		}
+		received++
+		if msg.Sequence > 0 && e.Sequence == msg.Sequence {
+			return true
+		}
+	}
+	return msg.Sequence == 0 && received >= position
+}
+
+// Prints the cut and in-flight messages recorded by each snapshot, and whether the cut is consistent.
+func printSnapshots() {
+	for s, snapshot := range allSnapshots {
+		fmt.Printf("Snapshot %d (initiated by P%d at event %d):\n", s, snapshot.Initiator, snapshot.Event)
+		for i, clock := range snapshot.Clocks {
+			if snapshot.Cut[i] >= 0 {
+				fmt.Printf("  P%d recorded %s after %d event(s)\n", i, vclock.FormatCounters(clock), snapshot.Cut[i])
+			}
+		}
+		for i := range snapshot.InFlight {
+			for j, messages := range snapshot.InFlight[i] {
+				for _, msg := range messages {
+					fmt.Printf("  in flight from P%d to P%d: %s\n", i, j, vclock.FormatCounters(msg.Clock.Counters))
+				}
+			}
+		}
+		if problems := checkSnapshot(snapshot); len(problems) > 0 {
+			fmt.Printf("  The cut is not consistent:\n")
+			for _, problem := range problems {
+				fmt.Printf("    %s\n", problem)
+			}
+		} else {
+			fmt.Printf("  The cut is consistent.\n")
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// Tests that a snapshot records a message sent before the cut and received after it as in flight.
+func TestSnapshotInFlight(t *testing.T) {
+	// Process 1 sends to process 0 before the marker from process 0 reaches it, but process 0 receives it after
+	// recording its state.
+	p0Events := []string{"S1", "C", "R1", "P"}
+	p1Events := []string{"R0", "S0", "S2"}
+	p2Events := []string{"P", "R1"}
+	events := [][]string{p0Events, p1Events, p2Events}
+
+	if err := RunVectorClock(events); err != nil {
+		t.Fatal(err)
+	}
+	if len(allSnapshots) != 1 {
+		t.Fatalf("Expected 1 snapshot, but got %d", len(allSnapshots))
+	}
+	snapshot := allSnapshots[0]
+	expectedCut := []int{1, 3, 2}
+	for i, n := range expectedCut {
+		if snapshot.Cut[i] != n {
+			t.Errorf("Expected process %d to record its state after %d event(s), but got %d", i, n, snapshot.Cut[i])
+		}
+	}
+	inFlight := snapshot.InFlight[1][0]
+	if len(inFlight) != 1 || inFlight[0].Clock.Counters[1] != 2 {
+		t.Errorf("Expected the second event of process 1 to be in flight to process 0, but got %v", inFlight)
+	}
+	if problems := checkSnapshot(snapshot); len(problems) != 0 {
+		t.Errorf("Expected the cut to be consistent, but got %v", problems)
+	}
+}
+
+// Tests that every snapshot in a run records a consistent cut, including processes that finish before the marker arrives.
+func TestSnapshotsConsistent(t *testing.T) {
+	p0Events := []string{"S1", "S2", "P"}
+	p1Events := []string{"C", "R0", "S2"}
+	p2Events := []string{"R0", "R1", "C"}
+	events := [][]string{p0Events, p1Events, p2Events}
+
+	for run := 0; run < 20; run++ {
+		if err := RunVectorClock(events); err != nil {
+			t.Fatal(err)
+		}
+		if len(allSnapshots) != 2 {
+			t.Fatalf("Expected 2 snapshots, but got %d", len(allSnapshots))
+		}
+		for s, snapshot := range allSnapshots {
+			if problems := checkSnapshot(snapshot); len(problems) != 0 {
+				t.Errorf("Expected snapshot %d to be consistent, but got %v", s, problems)
+			}
+		}
+		// Process 1 initiates the first snapshot before any of its events.
+		if allSnapshots[0].Cut[1] != 0 {
+			t.Errorf("Expected process 1 to record its state before any events, but got %d", allSnapshots[0].Cut[1])
+		}
+	}
+}
+
+// Tests that a message is still in flight when the receiver knows of its send only through another process.
+func TestSnapshotInFlightKnownIndirectly(t *testing.T) {
+	// Process 1 learns of process 0's send to it from process 2 before recording its state, and only then
+	// receives the message.
+	events := [][]string{{"S1", "S2"}, {"R2", "C", "R0"}, {"R0", "S1"}}
+	if err := RunVectorClock(events); err != nil {
+		t.Fatal(err)
+	}
+	snapshot := allSnapshots[0]
+	if inFlight := snapshot.InFlight[0][1]; len(inFlight) != 1 {
+		t.Errorf("Expected the message from process 0 to process 1 to be in flight, but got %v", inFlight)
+	}
+	if problems := checkSnapshot(snapshot); len(problems) != 0 {
+		t.Errorf("Expected the cut to be consistent, but got %v", problems)
+	}
+}
+
+// Tests that a cut including a receive but not its send is reported as inconsistent.
+func TestCheckSnapshotInconsistent(t *testing.T) {
+	snapshot := Snapshot{
+		Cut:      []int{0, 1},
+		Clocks:   [][]int{{0, 0}, {1, 1}},
+		InFlight: [][][]Message{{nil, nil}, {nil, nil}},
+	}
+	problems := checkSnapshot(snapshot)
+	if len(problems) != 1 || !strings.Contains(problems[0], "P1 recorded [1 1]") {
+		t.Errorf("Expected the receive without its send to be reported, but got %v", problems)
+	}
+}
+
+// Tests that a process waiting for snapshot markers from deadlocked processes is reported as stuck.
+func TestSnapshotDeadlock(t *testing.T) {
+	p0Events := []string{"C"}
+	p1Events := []string{"R2", "S2"}
+	p2Events := []string{"R1", "S1"}
+	events := [][]string{p0Events, p1Events, p2Events}
+
+	err := RunVectorClock(events)
+	if err == nil {
+		t.Fatal("Expected a deadlock, but the processes finished.")
+	}
+	if !strings.Contains(err.Error(), "process 0 is stuck after its last event waiting for a snapshot marker") {
+		t.Errorf("Expected process 0 to be waiting for a marker, but got: %v", err)
+	}
+}
+
+// Tests that snapshots cannot be mixed with forks.
+func TestSnapshotWithFork(t *testing.T) {
+	events := [][]string{[]string{"F1", "C"}, []string{"P"}}
+	if err := RunVectorClock(events); err == nil {
+		t.Errorf("Expected snapshots with forks to be rejected, but they were not.")
+	}
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// Running the processes over TCP, so each process can run as a separate OS process. Every process listens on
+// its own address, and the sender of each message dials the receiver, keeping one connection open for every
+// pair of processes so the messages between them stay in order. Messages are written as lines of JSON.
+
+// Envelope is a message as it is sent over TCP, along with the process that sent it.
+type Envelope struct {
+	From    int
+	Message Message
+}
+
+// TCPTransport carries messages between processes over TCP connections on the given addresses.
+type TCPTransport struct {
+	// The address each process listens on.
+	peers []string
+	// How long to wait for a peer to accept a connection or for a message to arrive.
+	timeout time.Duration
+	// The listener for each process that runs in this program.
+	listeners map[int]net.Listener
+	// The messages that have arrived for each process that runs in this program.
+	inboxes map[int]chan Envelope
+	// Messages that arrived for each process that runs in this program before it asked for them, by sender.
+	// Only used by the goroutine of the receiving process.
+	pending map[int][][]Message
+	mutex   sync.Mutex
+	// The connection from each sender to each receiver, opened by the first message between them.
+	encoders map[[2]int]*json.Encoder
+	// Every connection opened or accepted, closed along with the transport.
+	conns []net.Conn
+	// The first error the transport ran into.
+	err error
+	// Closed when the transport is closed.
+	done chan struct{}
+}
+
+// NewTCPTransport creates a transport for the processes in local, which listen on their addresses in peers.
+// Sending to a peer waits up to timeout for it to start listening, and receiving fails if nothing arrives in timeout.
+func NewTCPTransport(peers []string, local []int, timeout time.Duration) (*TCPTransport, error) {
+	t := &TCPTransport{
+		peers:     peers,
+		timeout:   timeout,
+		listeners: map[int]net.Listener{},
+		inboxes:   map[int]chan Envelope{},
+		pending:   map[int][][]Message{},
+		encoders:  map[[2]int]*json.Encoder{},
+		done:      make(chan struct{}),
+	}
+	for _, id := range local {
+		if id < 0 || id >= len(peers) {
+			t.Close()
+			return nil, fmt.Errorf("process %d has no address", id)
+		}
+		listener, err := net.Listen("tcp", peers[id])
+		if err != nil {
+			t.Close()
+			return nil, err
+		}
+		t.listeners[id] = listener
+		t.inboxes[id] = make(chan Envelope, len(peers))
+		t.pending[id] = make([][]Message, len(peers))
+		go t.accept(listener, t.inboxes[id])
+	}
+	return t, nil
+}
+
+// Accepts connections from senders, passing every message they send to the inbox.
+func (t *TCPTransport) accept(listener net.Listener, inbox chan Envelope) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		t.mutex.Lock()
+		t.conns = append(t.conns, conn)
+		t.mutex.Unlock()
+		go func() {
+			decoder := json.NewDecoder(conn)
+			for {
+				var envelope Envelope
+				if decoder.Decode(&envelope) != nil {
+					return
+				}
+				select {
+				case inbox <- envelope:
+				case <-t.done:
+					return
+				}
+			}
+		}()
+	}
+}
+
+// Records the first error the transport runs into.
+func (t *TCPTransport) fail(err error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.err == nil {
+		t.err = err
+	}
+}
+
+// Returns the connection from process id to dest, dialling dest until it accepts or the timeout passes.
+func (t *TCPTransport) connect(id int, dest int) (*json.Encoder, error) {
+	t.mutex.Lock()
+	encoder, ok := t.encoders[[2]int{id, dest}]
+	t.mutex.Unlock()
+	if ok {
+		return encoder, nil
+	}
+	deadline := time.Now().Add(t.timeout)
+	for {
+		conn, err := net.DialTimeout("tcp", t.peers[dest], t.timeout)
+		if err == nil {
+			t.mutex.Lock()
+			defer t.mutex.Unlock()
+			t.conns = append(t.conns, conn)
+			t.encoders[[2]int{id, dest}] = json.NewEncoder(conn)
+			return t.encoders[[2]int{id, dest}], nil
+		}
+		if time.Now().After(deadline) {
+			return nil, err
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// Send writes a message to the connection from process id to dest, opening it if needed.
+// Returns false if dest could not be reached.
+func (t *TCPTransport) Send(id int, dest int, index int, msg Message) bool {
+	encoder, err := t.connect(id, dest)
+	if err == nil {
+		err = encoder.Encode(Envelope{id, msg})
+	}
+	if err != nil {
+		t.fail(fmt.Errorf("process %d event %d: could not send to process %d: %v", id, index, dest, err))
+		return false
+	}
+	return true
+}
+
+// Returns the next message that arrives for process id, or false if none arrives before the timeout.
+func (t *TCPTransport) next(id int) (Envelope, bool) {
+	select {
+	case envelope := <-t.inboxes[id]:
+		return envelope, true
+	case <-time.After(t.timeout):
+		return Envelope{}, false
+	}
+}
+
+// Receive returns the next message from source to process id, keeping any messages from other processes that
+// arrive first for later. Returns false if no message from source arrives before the timeout.
+func (t *TCPTransport) Receive(id int, source int, index int) (Message, bool) {
+	pending := t.pending[id]
+	for len(pending[source]) == 0 {
+		envelope, ok := t.next(id)
+		if !ok {
+			t.fail(fmt.Errorf("process %d event %d: timed out waiting to receive from process %d", id, index, source))
+			return Message{}, false
+		}
+		pending[envelope.From] = append(pending[envelope.From], envelope.Message)
+	}
+	msg := pending[source][0]
+	pending[source] = pending[source][1:]
+	return msg, true
+}
+
+// ReceiveAny returns the next message to process id from any process, preferring messages that arrived
+// earlier and were kept for later. Returns false if no message arrives before the timeout.
+func (t *TCPTransport) ReceiveAny(id int, index int) (int, Message, bool) {
+	pending := t.pending[id]
+	for source := range pending {
+		if len(pending[source]) > 0 {
+			msg := pending[source][0]
+			pending[source] = pending[source][1:]
+			return source, msg, true
+		}
+	}
+	envelope, ok := t.next(id)
+	if !ok {
+		t.fail(fmt.Errorf("process %d event %d: timed out waiting to receive a message", id, index))
+		return 0, Message{}, false
+	}
+	return envelope.From, envelope.Message, true
+}
+
+// Close stops listening and closes every connection, returning the first error the transport ran into.
+// Closing the transport again does nothing.
+func (t *TCPTransport) Close() error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	select {
+	case <-t.done:
+		return t.err
+	default:
+		close(t.done)
+	}
+	for _, listener := range t.listeners {
+		listener.Close()
+	}
+	for _, conn := range t.conns {
+		conn.Close()
+	}
+	return t.err
+}
+
+// Timeline is the record of a process's run that a node running on its own reports to the coordinator.
+type Timeline struct {
+	ID     int
+	Clocks [][]int
+	Events []Event
+}
+
+// Sends the timeline of a process to the coordinator listening on addr, waiting up to timeout for it to accept.
+func reportTimeline(addr string, timeline Timeline, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		conn, err := net.DialTimeout("tcp", addr, timeout)
+		if err == nil {
+			defer conn.Close()
+			return json.NewEncoder(conn).Encode(timeline)
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("could not report to the coordinator: %v", err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// RunCoordinator listens on addr until every one of n processes has reported its timeline, then stores the
+// timelines in allClockValues and allTimelineEvents as if the processes had run in this program.
+func RunCoordinator(addr string, n int) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+	numProcesses = n
+	allClockValues = make([][][]int, n)
+	allTimelineEvents = make([][]Event, n)
+	for reported := 0; reported < n; {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		var timeline Timeline
+		err = json.NewDecoder(conn).Decode(&timeline)
+		conn.Close()
+		if err != nil {
+			return err
+		}
+		if timeline.ID < 0 || timeline.ID >= n || allClockValues[timeline.ID] != nil {
+			return fmt.Errorf("unexpected timeline from process %d", timeline.ID)
+		}
+		allClockValues[timeline.ID] = timeline.Clocks
+		allTimelineEvents[timeline.ID] = timeline.Events
+		reported++
+	}
+	return nil
+}
+
+// Runs process id on its own over TCP, with the other processes running elsewhere, and prints its timeline.
+// The timeline is then reported to the coordinator, unless coordinator is empty.
+func runNode(id int, peers []string, commands []string, coordinator string, timeout time.Duration, options []Option) error {
+	if id >= len(peers) {
+		return fmt.Errorf("process %d has no address", id)
+	}
+	t, err := NewTCPTransport(peers, []int{id}, timeout)
+	if err != nil {
+		return err
+	}
+	defer t.Close()
+	all := make([][]string, len(peers))
+	all[id] = commands
+	err = RunVectorClock(all, append(options, WithTransport(t), WithLocalProcesses([]int{id}))...)
+	if allClockValues[id] != nil {
+		printTimeline(id)
+	}
+	if runConfig.differential {
+		printWireStats()
+	}
+	if err != nil || coordinator == "" {
+		return err
+	}
+	return reportTimeline(coordinator, Timeline{id, allClockValues[id], allTimelineEvents[id]}, timeout)
+}
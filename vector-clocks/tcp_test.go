@@ -0,0 +1,128 @@
+package main
+
+import (
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Returns n addresses on localhost that are free to listen on.
+func freeAddresses(t *testing.T, n int) []string {
+	addresses := make([]string, n)
+	for i := range addresses {
+		listener, err := net.Listen("tcp", "localhost:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		addresses[i] = listener.Addr().String()
+		listener.Close()
+	}
+	return addresses
+}
+
+// Tests that running every process over TCP gives the same clocks as running them over channels.
+func TestTCPTransportSameClocks(t *testing.T) {
+	p0Events := []string{"S1", "R1", "B", "R1"}
+	p1Events := []string{"R0", "S0", "S0", "R0", "S2"}
+	p2Events := []string{"P", "R0", "R1"}
+	events := [][]string{p0Events, p1Events, p2Events}
+
+	if err := RunVectorClock(events); err != nil {
+		t.Fatal(err)
+	}
+	expected := allClockValues
+
+	tcp, err := NewTCPTransport(freeAddresses(t, 3), []int{0, 1, 2}, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := RunVectorClock(events, WithTransport(tcp)); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(allClockValues, expected) {
+		t.Errorf("Expected the clocks %v over TCP, but got %v", expected, allClockValues)
+	}
+}
+
+// Tests that a process running on its own times out waiting for a process that never sends.
+func TestTCPTransportTimeout(t *testing.T) {
+	tcp, err := NewTCPTransport(freeAddresses(t, 2), []int{0}, 100*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = RunVectorClock([][]string{[]string{"P", "R1"}, nil}, WithTransport(tcp), WithLocalProcesses([]int{0}))
+	if err == nil || !strings.Contains(err.Error(), "timed out waiting to receive from process 1") {
+		t.Errorf("Expected process 0 to time out, but got %v", err)
+	}
+	if len(allClockValues[0]) != 1 {
+		t.Errorf("Expected the event before the receive to be recorded, but got %v", allClockValues[0])
+	}
+}
+
+// Tests that processes running on their own cannot use events that need shared memory.
+func TestLocalProcessesShared(t *testing.T) {
+	for _, command := range []string{"L", "Wx", "F1", "C"} {
+		err := RunVectorClock([][]string{[]string{"P", command}, nil}, WithLocalProcesses([]int{0}))
+		if err == nil {
+			t.Errorf("Expected %s to be rejected, but it was not.", command)
+		}
+	}
+}
+
+// Tests that the coordinator collects the timeline reported by every process.
+func TestCoordinator(t *testing.T) {
+	addr := freeAddresses(t, 1)[0]
+	done := make(chan error)
+	go func() {
+		done <- RunCoordinator(addr, 2)
+	}()
+	timelines := []Timeline{
+		{1, [][]int{{1, 1}}, []Event{{Type: 'R', Peer: 0}}},
+		{0, [][]int{{1, 0}}, []Event{{Type: 'S', Peer: 1}}},
+	}
+	for _, timeline := range timelines {
+		if err := reportTimeline(addr, timeline, 5*time.Second); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(allClockValues, [][][]int{{{1, 0}}, {{1, 1}}}) || allTimelineEvents[1][0].Type != 'R' {
+		t.Errorf("Expected both timelines to be collected, but got %v and %v", allClockValues, allTimelineEvents)
+	}
+}
+
+// Tests that the line of a process running on its own can send to and receive from the other processes, which
+// run elsewhere.
+func TestRunNodeScript(t *testing.T) {
+	addresses := freeAddresses(t, 2)
+	script, _, err := parseScript(strings.NewReader("S1 R1\n"), 0, len(addresses))
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	if _, _, err := parseScript(strings.NewReader("S2\n"), 0, len(addresses)); err == nil {
+		t.Errorf("Expected a send to a process without an address to be rejected, but it was not.")
+	}
+
+	// Process 1 echoes the message from process 0 back to it.
+	peer, err := NewTCPTransport(addresses, []int{1}, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer peer.Close()
+	go func() {
+		if msg, ok := peer.Receive(1, 0, 0); ok {
+			msg.Clock.Counters = []int{1, 2}
+			peer.Send(1, 0, 1, msg)
+		}
+	}()
+	if err := runNode(0, addresses, script.Commands[0], "", 5*time.Second, nil); err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	if !reflect.DeepEqual(allClockValues[0], [][]int{{1, 0}, {2, 2}}) {
+		t.Errorf("Expected process 0 to receive the echo, but got %v", allClockValues[0])
+	}
+}
@@ -0,0 +1,369 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/kulvirs/Concurrency-A2/vector-clocks/vclock"
+)
+
+// JSON traces of a run. A trace records every process's timeline: each event with its type, peer, labels and the
+// vector clock it resulted in. Events that pass clocks between processes share a message id: a send or broadcast
+// and every receive and delivery of it, and an unlock and the lock that took the lock next. A join passes the
+// final clock of the process it joins, and a forked process starts with the clock of the fork, so these need no
+// ids. Loading a trace recomputes every clock from the events and the message ids alone, and checks the result
+// against the recorded clocks, so a trace can be analyzed, compared and used in tests without running it again.
+
+// Trace is a recorded run of the simulation.
+type Trace struct {
+	Processes []TraceProcess
+}
+
+// TraceProcess is the timeline of one process in a trace.
+type TraceProcess struct {
+	// The number of the process, its position in the trace.
+	ID int
+	// The name of the process, empty for a process without a name.
+	Name   string
+	Events []TraceEvent
+}
+
+// TraceEvent is one event of a timeline in a trace.
+type TraceEvent struct {
+	// The type of event, written as in Event: "P", "S", "R", "B", "D", "F", "J", "r", "w", "L" or "U".
+	Type string
+	// The process the event sends to, receives or delivers from, forks or joins, or the process itself. A receive
+	// from any process that timed out has the peer -1.
+	Peer int
+	// Set for receives and deliveries of a broadcast.
+	Broadcast bool
+	// Set for receives from any process (R*).
+	Any bool
+	// Set for receives that timed out.
+	TimedOut bool
+	// The local variables a local event assigns, e.g. "x=3 y=1".
+	Assign string
+	// The shared variable accessed, or the name of the lock.
+	Variable string
+	// The event's own label, and the payload of the message it received.
+	Label   string
+	Payload string
+	// The vector clock the event resulted in.
+	Clock []int
+	// The id of the message the event sends or receives, counting from 1, or 0 if it has none.
+	Message int
+}
+
+// Returns a trace of the timelines recorded by the last run.
+func buildTrace() Trace {
+	// Every send and broadcast gets the next id, in process order, followed by every unlock that hands a lock on.
+	ids := map[EventID]int{}
+	next := 1
+	for i, timelineEvents := range allTimelineEvents {
+		for j, e := range timelineEvents {
+			if e.Type == 'S' || e.Type == 'B' {
+				ids[EventID{i, j}] = next
+				next++
+			}
+		}
+	}
+	for _, edge := range messageEdges() {
+		ids[edge[1]] = ids[edge[0]]
+	}
+	// The k-th broadcast from process i that a process delivers is the k-th broadcast process i sent.
+	for j, timelineEvents := range allTimelineEvents {
+		delivered := make([]int, len(allTimelineEvents))
+		for k, e := range timelineEvents {
+			if e.Type == 'D' {
+				ids[EventID{j, k}] = ids[nthEvent(e.Peer, 'B', delivered[e.Peer])]
+				delivered[e.Peer]++
+			}
+		}
+	}
+	for _, handoff := range allLockHandoffs {
+		if ids[handoff[0]] == 0 {
+			ids[handoff[0]] = next
+			next++
+		}
+		ids[handoff[1]] = ids[handoff[0]]
+	}
+
+	trace := Trace{[]TraceProcess{}}
+	for i, timelineEvents := range allTimelineEvents {
+		process := TraceProcess{i, "", []TraceEvent{}}
+		if i < len(runConfig.names) {
+			process.Name = runConfig.names[i]
+		}
+		for j, e := range timelineEvents {
+			process.Events = append(process.Events, TraceEvent{string(e.Type), e.Peer, e.Broadcast, e.Any, e.TimedOut, e.Assign,
+				e.Variable, e.Label, e.Payload, allClockValues[i][j], ids[EventID{i, j}]})
+		}
+		trace.Processes = append(trace.Processes, process)
+	}
+	return trace
+}
+
+// Returns the k-th event of the given type in the timeline of a process, counting from 0.
+func nthEvent(process int, eventType byte, k int) EventID {
+	for j, e := range allTimelineEvents[process] {
+		if e.Type == eventType {
+			if k == 0 {
+				return EventID{process, j}
+			}
+			k--
+		}
+	}
+	return EventID{process, -1}
+}
+
+// Writes a trace of the recorded timelines as indented JSON.
+func writeTrace(w io.Writer) error {
+	encoded, err := json.MarshalIndent(buildTrace(), "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", encoded)
+	return err
+}
+
+// Reads a trace written by writeTrace.
+func readTrace(r io.Reader) (Trace, error) {
+	var trace Trace
+	if err := json.NewDecoder(r).Decode(&trace); err != nil {
+		return trace, fmt.Errorf("invalid trace: %v", err)
+	}
+	return trace, nil
+}
+
+// Returns the events of a trace, checking that every event is well formed.
+func traceEvents(trace Trace) ([][]Event, error) {
+	events := make([][]Event, len(trace.Processes))
+	for i, process := range trace.Processes {
+		if process.ID != i {
+			return nil, fmt.Errorf("process %d is listed as process %d", i, process.ID)
+		}
+		events[i] = []Event{}
+		for j, te := range process.Events {
+			id := EventID{i, j}
+			if len(te.Type) != 1 || !strings.Contains("PSRBDFJrwLU", te.Type) {
+				return nil, fmt.Errorf("%v has an invalid type %q", id, te.Type)
+			}
+			timedOutAny := te.Type == "R" && te.Any && te.TimedOut && te.Peer == anySource
+			if (te.Peer < 0 || te.Peer >= len(trace.Processes)) && !timedOutAny {
+				return nil, fmt.Errorf("%v has an invalid peer %d", id, te.Peer)
+			}
+			if len(te.Clock) != len(trace.Processes) {
+				return nil, fmt.Errorf("%v has a clock with %d entries for %d processes", id, len(te.Clock), len(trace.Processes))
+			}
+			events[i] = append(events[i], Event{Type: te.Type[0], Peer: te.Peer, Broadcast: te.Broadcast, Any: te.Any, TimedOut: te.TimedOut,
+				Assign: te.Assign, Variable: te.Variable, Label: te.Label, Payload: te.Payload})
+		}
+	}
+	return events, nil
+}
+
+// Returns true if the event sends the clock it results in under its message id.
+func sendsMessage(e Event) bool {
+	return e.Type == 'S' || e.Type == 'B' || e.Type == 'U'
+}
+
+// Returns true if the event merges the clock sent under its message id. A receive of a broadcast does not,
+// since a broadcast is only merged when it is delivered, and neither does a receive that timed out.
+func mergesMessage(e Event) bool {
+	return (e.Type == 'R' && !e.Broadcast && !e.TimedOut) || e.Type == 'D' || e.Type == 'L'
+}
+
+// Returns the event that sent each message id of a trace. Returns an error if a message is sent more than once, or
+// if a message is received but never sent.
+func traceSenders(trace Trace, events [][]Event) (map[int]EventID, error) {
+	senders := map[int]EventID{}
+	for i, process := range trace.Processes {
+		for j, te := range process.Events {
+			if te.Message == 0 || !sendsMessage(events[i][j]) {
+				continue
+			}
+			if other, ok := senders[te.Message]; ok {
+				return nil, fmt.Errorf("message %d is sent by both %v and %v", te.Message, other, EventID{i, j})
+			}
+			senders[te.Message] = EventID{i, j}
+		}
+	}
+	for i, process := range trace.Processes {
+		for j, te := range process.Events {
+			if _, ok := senders[te.Message]; mergesMessage(events[i][j]) && te.Message != 0 && !ok {
+				return nil, fmt.Errorf("message %d is received by %v but never sent", te.Message, EventID{i, j})
+			}
+			if mergesMessage(events[i][j]) && events[i][j].Type == 'R' && te.Message == 0 {
+				return nil, fmt.Errorf("%v receives a message without an id", EventID{i, j})
+			}
+		}
+	}
+	return senders, nil
+}
+
+// Recomputes the vector clock of every event of a trace from its events and message ids. Returns an error if a
+// message is received but never sent, or if the events wait on each other in a cycle.
+func recomputeClocks(trace Trace, events [][]Event) ([][][]int, error) {
+	n := len(trace.Processes)
+	senders, err := traceSenders(trace, events)
+	if err != nil {
+		return nil, err
+	}
+
+	// Forked processes start with the clock of their fork, once it is known.
+	forks := map[int]EventID{}
+	for i, processEvents := range events {
+		for j, e := range processEvents {
+			if e.Type == 'F' {
+				forks[e.Peer] = EventID{i, j}
+			}
+		}
+	}
+	clocks := make([][][]int, n)
+	// Returns the clock of an event, or nil if it has not been computed yet.
+	clockOf := func(id EventID) []int {
+		if id.Index < len(clocks[id.Process]) {
+			return clocks[id.Process][id.Index]
+		}
+		return nil
+	}
+	for progress := true; progress; {
+		progress = false
+		for i := range events {
+			for len(clocks[i]) < len(events[i]) {
+				j := len(clocks[i])
+				var previous, merged []int
+				if j > 0 {
+					previous = clocks[i][j-1]
+				} else if fork, ok := forks[i]; ok {
+					if previous = clockOf(fork); previous == nil {
+						break
+					}
+				} else {
+					previous = make([]int, n)
+				}
+				e := events[i][j]
+				if mergesMessage(e) && trace.Processes[i].Events[j].Message != 0 {
+					if merged = clockOf(senders[trace.Processes[i].Events[j].Message]); merged == nil {
+						break
+					}
+				} else if e.Type == 'J' {
+					// A join merges the final clock of the joined process, which is the clock of its fork if it
+					// has no events.
+					if len(clocks[e.Peer]) < len(events[e.Peer]) {
+						break
+					}
+					if len(events[e.Peer]) > 0 {
+						merged = clocks[e.Peer][len(events[e.Peer])-1]
+					} else if fork, ok := forks[e.Peer]; !ok {
+						merged = make([]int, n)
+					} else if merged = clockOf(fork); merged == nil {
+						break
+					}
+				}
+				clock := vclock.VectorClock{ID: i, Counters: append([]int{}, previous...)}
+				if merged != nil {
+					clock.Merge(&vclock.VectorClock{ID: e.Peer, Counters: merged})
+				}
+				clock.Tick()
+				clocks[i] = append(clocks[i], clock.Counters)
+				progress = true
+			}
+		}
+	}
+	for i := range events {
+		if len(clocks[i]) < len(events[i]) {
+			return nil, fmt.Errorf("%v waits on events that never happen", EventID{i, len(clocks[i])})
+		}
+	}
+	return clocks, nil
+}
+
+// Returns the events and clocks of a trace. Returns an error listing every event whose recorded clock is not the
+// one recomputed from the trace.
+func checkTrace(trace Trace) ([][]Event, [][][]int, error) {
+	events, err := traceEvents(trace)
+	if err != nil {
+		return nil, nil, err
+	}
+	clocks, err := recomputeClocks(trace, events)
+	if err != nil {
+		return nil, nil, err
+	}
+	problems := []string{}
+	for i, process := range trace.Processes {
+		for j, te := range process.Events {
+			if vclock.CompareCounters(te.Clock, clocks[i][j]) != vclock.Equal {
+				problems = append(problems, fmt.Sprintf("%v is recorded with clock %s, but recomputes to %s",
+					EventID{i, j}, vclock.FormatCounters(te.Clock), vclock.FormatCounters(clocks[i][j])))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return nil, nil, errors.New("the recorded clocks do not match the events:\n  " + strings.Join(problems, "\n  "))
+	}
+	return events, clocks, nil
+}
+
+// Returns the events of a trace and the clocks recorded for them, without recomputing the clocks, so that a trace
+// recorded under different rules for updating clocks can still be read. Returns an error if the events are not
+// well formed or their message ids do not link every receive to a send.
+func recordedClocks(trace Trace) ([][]Event, [][][]int, error) {
+	events, err := traceEvents(trace)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := traceSenders(trace, events); err != nil {
+		return nil, nil, err
+	}
+	clocks := make([][][]int, len(trace.Processes))
+	for i, process := range trace.Processes {
+		clocks[i] = [][]int{}
+		for _, te := range process.Events {
+			clocks[i] = append(clocks[i], te.Clock)
+		}
+	}
+	return events, clocks, nil
+}
+
+// Loads a trace in place of a run, so the timelines can be printed and analyzed as if the trace had just been
+// run. Returns an error if its recorded clocks do not match its events.
+func loadTrace(trace Trace) error {
+	events, clocks, err := checkTrace(trace)
+	if err != nil {
+		return err
+	}
+	runConfig = config{names: []string{}}
+	numProcesses = len(trace.Processes)
+	allClockValues = clocks
+	allTimelineEvents = events
+	for _, process := range trace.Processes {
+		runConfig.names = append(runConfig.names, process.Name)
+	}
+	allSnapshots = nil
+	allRaces = nil
+	return nil
+}
+
+// Reads the trace in the given file.
+func readTraceFile(file string) (Trace, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return Trace{}, err
+	}
+	defer f.Close()
+	return readTrace(f)
+}
+
+// Reads and loads the trace in the given file.
+func loadTraceFile(file string) error {
+	trace, err := readTraceFile(file)
+	if err != nil {
+		return err
+	}
+	return loadTrace(trace)
+}
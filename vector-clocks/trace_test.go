@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Runs the events and returns the trace of the run after writing and reading it back.
+func roundTrip(t *testing.T, events [][]string, options ...Option) Trace {
+	if err := RunVectorClock(events, options...); err != nil {
+		t.Fatalf("Expected %v to run but got %v", events, err)
+	}
+	var buffer bytes.Buffer
+	if err := writeTrace(&buffer); err != nil {
+		t.Fatalf("Expected no error writing the trace but got %v", err)
+	}
+	trace, err := readTrace(&buffer)
+	if err != nil {
+		t.Fatalf("Expected no error reading the trace but got %v", err)
+	}
+	return trace
+}
+
+// Tests that loading the trace of a run gives back the same timelines and clocks, for runs with every kind of event
+// that passes a clock between processes.
+func TestTraceRoundTrip(t *testing.T) {
+	scripts := [][][]string{
+		{{`S1:"hi"`, "R1", "P"}, {"R0", "P", "x=2", "S0"}},
+		{{"B", "R*", "R*"}, {"R0", "S0"}, {"R0", "S0"}},
+		{{"F2", "S1", "J2"}, {"R0", "R2"}, {"S1", "P"}},
+		{{"L", "Wx", "U", "Rx"}, {"L", "Wx", "U"}, {"L", "U"}},
+	}
+	for _, events := range scripts {
+		trace := roundTrip(t, events)
+		clocks := allClockValues
+		timelineEvents := allTimelineEvents
+		if err := loadTrace(trace); err != nil {
+			t.Errorf("Expected the trace of %v to load but got %v", events, err)
+			continue
+		}
+		if !reflect.DeepEqual(allClockValues, clocks) {
+			t.Errorf("Expected the clocks of %v to be %v after loading but got %v", events, clocks, allClockValues)
+		}
+		if !reflect.DeepEqual(allTimelineEvents, timelineEvents) {
+			t.Errorf("Expected the events of %v to be %v after loading but got %v", events, timelineEvents, allTimelineEvents)
+		}
+	}
+
+	trace := roundTrip(t, [][]string{{"S1"}, {"R0"}}, WithProcessNames([]string{"shop", ""}))
+	if err := loadTrace(trace); err != nil || !reflect.DeepEqual(runConfig.names, []string{"shop", ""}) {
+		t.Errorf("Expected the names to be loaded but got %v and %v", runConfig.names, err)
+	}
+}
+
+// Tests that every receive shares the message id of its send, and that a lock shares the id of the unlock that
+// released it.
+func TestTraceMessageIDs(t *testing.T) {
+	trace := roundTrip(t, [][]string{{"S1", "L", "U"}, {"R0", "L", "U"}})
+	send, receive := trace.Processes[0].Events[0], trace.Processes[1].Events[0]
+	if send.Message == 0 || send.Message != receive.Message {
+		t.Errorf("Expected the send and receive to share a message id but got %d and %d", send.Message, receive.Message)
+	}
+	ids := map[int]int{}
+	for _, process := range trace.Processes {
+		for _, e := range process.Events {
+			if e.Message != 0 && (e.Type == "L" || e.Type == "U") {
+				ids[e.Message]++
+			}
+		}
+	}
+	if len(ids) != 1 {
+		t.Errorf("Expected one unlock to hand the lock to the other process but got %v", ids)
+	}
+}
+
+// Tests that a trace whose clocks do not follow from its events is reported, naming only the events that are wrong.
+func TestTraceTampered(t *testing.T) {
+	trace := roundTrip(t, [][]string{{"S1", "P"}, {"R0", "P"}})
+	trace.Processes[1].Events[0].Clock[0] = 5
+	err := loadTrace(trace)
+	if err == nil || !strings.Contains(err.Error(), "P1.e0 is recorded with clock [5 1], but recomputes to [1 1]") {
+		t.Errorf("Expected the tampered clock to be reported but got %v", err)
+	}
+	if err != nil && strings.Contains(err.Error(), "P1.e1") {
+		t.Errorf("Expected only the tampered clock to be reported but got %v", err)
+	}
+}
+
+// Tests that traces that are not well formed are rejected.
+func TestTraceInvalid(t *testing.T) {
+	invalid := map[string]string{
+		"invalid trace":                       `{"Processes": [`,
+		"P0.e0 has an invalid type \"X\"":     `{"Processes": [{"ID": 0, "Events": [{"Type": "X", "Clock": [1]}]}]}`,
+		"P0.e0 has an invalid peer 3":         `{"Processes": [{"ID": 0, "Events": [{"Type": "S", "Peer": 3, "Clock": [1]}]}]}`,
+		"clock with 2 entries for 1":          `{"Processes": [{"ID": 0, "Events": [{"Type": "P", "Clock": [1, 0]}]}]}`,
+		"message 4 is received by P0.e0":      `{"Processes": [{"ID": 0, "Events": [{"Type": "R", "Clock": [1], "Message": 4}]}]}`,
+		"P0.e0 receives a message without":    `{"Processes": [{"ID": 0, "Events": [{"Type": "R", "Clock": [1]}]}]}`,
+		"process 1 is listed as process 0":    `{"Processes": [{"ID": 0}, {"ID": 0}]}`,
+		"P0.e0 waits on events that never":    `{"Processes": [{"ID": 0, "Events": [{"Type": "R", "Peer": 1, "Clock": [1, 2], "Message": 2}, {"Type": "S", "Peer": 1, "Clock": [2, 2], "Message": 1}]}, {"ID": 1, "Events": [{"Type": "R", "Clock": [2, 1], "Message": 1}, {"Type": "S", "Clock": [2, 2], "Message": 2}]}]}`,
+		"message 1 is sent by both P0.e0 and": `{"Processes": [{"ID": 0, "Events": [{"Type": "S", "Clock": [1], "Message": 1}, {"Type": "S", "Clock": [2], "Message": 1}]}]}`,
+	}
+	for expected, encoded := range invalid {
+		trace, err := readTrace(strings.NewReader(encoded))
+		if err == nil {
+			err = loadTrace(trace)
+		}
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected %s to fail with %q but got %v", encoded, expected, err)
+		}
+	}
+}
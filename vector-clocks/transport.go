@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Transport carries messages between the processes of the simulation. Messages between each pair of processes
+// must be delivered in the order they were sent.
+type Transport interface {
+	// Send sends a message from process id to dest on behalf of the event at the given index.
+	// Returns false if the message could not be sent.
+	Send(id int, dest int, index int, msg Message) bool
+	// Receive receives the next message from source to process id on behalf of the event at the given index.
+	// Returns false if no message could be received.
+	Receive(id int, source int, index int) (Message, bool)
+	// ReceiveAny receives the next message from any process to process id on behalf of the event at the given
+	// index, and returns the process that sent it. Returns false if no message could be received.
+	ReceiveAny(id int, index int) (int, Message, bool)
+	// Close shuts the transport down at the end of a run, returning the error that stopped it, if any.
+	Close() error
+}
+
+// The peer of a receive from any process (R*) in the input, before the process it receives from is known.
+const anySource = -1
+
+// Stores the transport for the current run.
+var transport Transport
+
+// Carries messages over the channels in the channels matrix, with every operation going through the deadlock
+// monitor. This is the default transport, with every process running as a goroutine in the same program.
+type channelTransport struct{}
+
+// Send puts a message on the channel to dest, waiting while the channel is full.
+// Returns false if the simulation deadlocked before the message could be sent.
+func (channelTransport) Send(id int, dest int, index int, msg Message) bool {
+	return deadlockMonitor.do(id, index, dest, []string{channelObject(id, dest)}, func() bool {
+		return len(channels[id][dest]) < cap(channels[id][dest])
+	}, func() bool {
+		select {
+		case channels[id][dest] <- msg:
+			return true
+		default:
+			return false
+		}
+	})
+}
+
+// Receive takes the next message from the channel from source, waiting until there is one.
+// Returns false if the simulation deadlocked before a message arrived.
+func (channelTransport) Receive(id int, source int, index int) (Message, bool) {
+	var msg Message
+	// The receive gets the next message from source whatever order the other steps are taken in, so it is
+	// independent of every other process's steps.
+	received := deadlockMonitor.do(id, index, source, nil, func() bool {
+		return len(channels[source][id]) > 0
+	}, func() bool {
+		select {
+		case msg = <-channels[source][id]:
+			return true
+		default:
+			return false
+		}
+	})
+	return msg, received
+}
+
+// ReceiveAny takes the next message from a channel to process id that has one, waiting until there is one.
+// When more than one channel has a message, a select picks one at random, except under the scheduler, which
+// takes the message from the lowest numbered process so that a run can be replayed. The scheduler still lets
+// each of the other messages be taken first by running the receive before the lower numbered sends.
+// Returns false if the simulation deadlocked before a message arrived.
+func (channelTransport) ReceiveAny(id int, index int) (int, Message, bool) {
+	var msg Message
+	var source int
+	// Which message arrives first depends on the order of the sends to this process.
+	objects := []string{}
+	cases := []reflect.SelectCase{}
+	sources := []int{}
+	for i := 0; i < numProcesses; i++ {
+		if i != id {
+			objects = append(objects, channelObject(i, id))
+			cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(channels[i][id])})
+			sources = append(sources, i)
+		}
+	}
+	cases = append(cases, reflect.SelectCase{Dir: reflect.SelectDefault})
+	received := deadlockMonitor.do(id, index, anySource, objects, func() bool {
+		for _, source := range sources {
+			if len(channels[source][id]) > 0 {
+				return true
+			}
+		}
+		return false
+	}, func() bool {
+		if runConfig.scheduler != nil {
+			for _, source = range sources {
+				select {
+				case msg = <-channels[source][id]:
+					return true
+				default:
+				}
+			}
+			return false
+		}
+		chosen, value, _ := reflect.Select(cases)
+		if chosen == len(sources) {
+			return false
+		}
+		source, msg = sources[chosen], value.Interface().(Message)
+		return true
+	})
+	return source, msg, received
+}
+
+// Close does nothing, since a deadlock is reported by the deadlock monitor.
+func (channelTransport) Close() error {
+	return nil
+}
+
+// Returns the name of the channel from one process to another, touched by the sends on it and by receives from
+// any process at the other end.
+func channelObject(from int, to int) string {
+	return fmt.Sprintf("channel %d>%d", from, to)
+}
+
+// Returns a label naming the process a receive from any process (R*) took its message from, or an empty string
+// for any other event. A broadcast's own label already names its sender.
+func anyLabel(e Event) string {
+	if !e.Any || e.Broadcast || e.TimedOut {
+		return ""
+	}
+	return fmt.Sprintf("from P%d", e.Peer)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kulvirs/Concurrency-A2/sched"
+)
+
+// Tests that a receive from any process takes a message from each sender, and that the timeline records which
+// sender each message came from.
+func TestReceiveAny(t *testing.T) {
+	err := RunVectorClock([][]string{{"R*", "R*", "S1"}, {"S0", "R0"}, {`S0:"hi"`}})
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	events := allTimelineEvents[0]
+	sources := []int{events[0].Peer, events[1].Peer}
+	if sources[0] == sources[1] || sources[0]+sources[1] != 3 {
+		t.Fatalf("Expected one message from each of P1 and P2 but got them from %v", sources)
+	}
+	if !events[0].Any || events[0].String() != "R*" || eventLabel(events[0]) == "" {
+		t.Errorf("Expected the receive to be shown as R* with its sender, but got %v (%s)", events[0], eventLabel(events[0]))
+	}
+	if !reflect.DeepEqual(allClockValues[0][1], []int{2, 1, 1}) {
+		t.Errorf("Expected [2 1 1] after both receives but got %v", allClockValues[0][1])
+	}
+	for _, e := range events[:2] {
+		if e.Peer == 2 && (e.Payload != "hi" || eventLabel(e) != `from P2, received "hi"`) {
+			t.Errorf("Expected the message from P2 to carry its payload, but got %q", eventLabel(e))
+		}
+	}
+}
+
+// Tests that receives from any process must take exactly the messages that no named receive takes.
+func TestReceiveAnyValidation(t *testing.T) {
+	valid := [][][]string{
+		{{"R*", "R1"}, {"S0", "S0"}},
+		{{"R*", "R*"}, {"B"}, {"R1", "S0"}},
+	}
+	for _, events := range valid {
+		if err := RunVectorClock(events); err != nil {
+			t.Errorf("Expected %v to run but got %v", events, err)
+		}
+	}
+	invalid := map[string][][]string{
+		"process 0 receives 1 message(s) from any process, but 2": {{"R*"}, {"S0"}, {"S0"}},
+		"process 0 receives 2 message(s) from any process, but 1": {{"R*", "R*"}, {"S0"}},
+		"(unmatched R1)": {{"R*", "R1"}, {"P"}, {"S0"}},
+	}
+	for expected, events := range invalid {
+		err := RunVectorClock(events)
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected %v to fail with %q but got %v", events, expected, err)
+		}
+	}
+}
+
+// Tests that exploring finds a run for each sender a receive from any process can take, that the run taking the
+// wrong message deadlocks, and that its schedule replays the same choice.
+func TestReceiveAnyExplore(t *testing.T) {
+	events := [][]string{{"R*", "R1"}, {"S0"}, {"S0"}}
+	exploration, err := Explore(events, nil, 0)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	if !exploration.Complete || exploration.Outcomes != 2 || len(exploration.Violations) != 1 {
+		t.Fatalf("Expected 2 outcomes and a deadlock but got %+v", exploration)
+	}
+	schedule := exploration.Violations[0].Schedule
+	for i := 0; i < 2; i++ {
+		err = RunVectorClock(events, WithScheduler(sched.NewReplay(schedule)))
+		if _, ok := err.(*DeadlockError); !ok || allTimelineEvents[0][0].Peer != 1 {
+			t.Fatalf("Expected the schedule to replay the receive from P1 and the deadlock, but got %v and %v", allTimelineEvents[0], err)
+		}
+		if !strings.Contains(err.Error(), "waiting to receive from process 1") {
+			t.Errorf("Expected the deadlock to name the named receive, but got %v", err)
+		}
+	}
+	err = RunVectorClock([][]string{{"R*", "S1"}, {"R*", "S0"}})
+	if err == nil || !strings.Contains(err.Error(), "(R*) waiting to receive from any process") {
+		t.Errorf("Expected both receives from any process to deadlock but got %v", err)
+	}
+}
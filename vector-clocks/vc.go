@@ -1,152 +1,987 @@
 package main
 
 import (
-	"bufio"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
-	"strconv"
 	"strings"
 	"sync"
+	"time"
+
+	"github.com/kulvirs/Concurrency-A2/sched"
+	"github.com/kulvirs/Concurrency-A2/vector-clocks/vclock"
 )
 
-// Number of processes in the vector clock simulation.
-const numProcesses = 3
+// Number of processes in the vector clock simulation, determined by the number of event lists given.
+var numProcesses int
+
+// Option configures a run of the simulation.
+type Option func(*config)
+
+// Settings for a run of the simulation.
+type config struct {
+	// Whether nodes keep matrix clocks as well as vector clocks.
+	matrix bool
+	// Whether nodes keep Lamport clocks as well as vector clocks.
+	lamport bool
+	// Whether nodes keep hybrid logical clocks as well as vector clocks.
+	hybrid bool
+	// The simulated physical clock of each process, used with hybrid logical clocks.
+	physicalClocks []PhysicalClock
+	// Whether nodes keep interval tree clocks as well as vector clocks.
+	itc bool
+	// The transport messages are sent over, nil for the default channel transport.
+	transport Transport
+	// The processes to run in this program, nil to run every process.
+	local []int
+	// Runs the processes one step at a time when set.
+	scheduler *sched.Scheduler
+	// The name of each process, empty for processes without a name.
+	names []string
+	// Whether S events send only the vector clock entries that changed since the last message to the same process.
+	differential bool
+	// The number of entries in each node's plausible clock, 0 unless running with plausible clocks.
+	plausible int
+	// The number of counters in each node's Bloom clock, 0 unless running with Bloom clocks.
+	bloomCells int
+	// The number of counters each event increments in a Bloom clock.
+	bloomHashes int
+	// The faults injected into messages, nil to send them over reliable channels.
+	faults *Faults
+}
+
+// Stores the settings for the current run.
+var runConfig config
+
+// WithMatrixClocks makes every node keep a matrix clock alongside its vector clock, recorded in allMatrixValues.
+func WithMatrixClocks() Option {
+	return func(c *config) {
+		c.matrix = true
+	}
+}
+
+// WithLamportClocks makes every node keep a Lamport clock alongside its vector clock, recorded in allLamportValues.
+func WithLamportClocks() Option {
+	return func(c *config) {
+		c.lamport = true
+	}
+}
+
+// WithHybridClocks makes every node keep a hybrid logical clock alongside its vector clock, recorded in allHLCValues.
+// The physical time of each node is read from its simulated physical clock in physicalClocks. Nodes without an
+// entry have a perfect physical clock.
+func WithHybridClocks(physicalClocks []PhysicalClock) Option {
+	return func(c *config) {
+		c.hybrid = true
+		c.physicalClocks = physicalClocks
+	}
+}
+
+// WithIntervalTreeClocks makes every node keep an interval tree clock alongside its vector clock, recorded in allITCValues.
+func WithIntervalTreeClocks() Option {
+	return func(c *config) {
+		c.itc = true
+	}
+}
+
+// WithPlausibleClocks makes every node keep a plausible clock with r entries alongside its vector clock, recorded
+// in allPlausibleValues.
+func WithPlausibleClocks(r int) Option {
+	return func(c *config) {
+		c.plausible = r
+	}
+}
+
+// WithBloomClocks makes every node keep a Bloom clock with m counters, each event incrementing k of them, alongside
+// its vector clock, recorded in allBloomValues.
+func WithBloomClocks(m int, k int) Option {
+	return func(c *config) {
+		c.bloomCells = m
+		c.bloomHashes = k
+	}
+}
+
+// WithTransport sends messages between processes over the given transport instead of channels.
+func WithTransport(t Transport) Option {
+	return func(c *config) {
+		c.transport = t
+	}
+}
+
+// WithLocalProcesses only runs the given processes. The other processes run elsewhere, such as in other OS
+// processes, and are reached through the transport. Only the events of the local processes are checked, and
+// they cannot use events that need memory shared with the other processes: snapshots, forks, joins, locks
+// and shared variables.
+func WithLocalProcesses(ids []int) Option {
+	return func(c *config) {
+		c.local = ids
+	}
+}
 
-// VectorClock represents the vector clock that is kept at each node to maintain ordering.
-type VectorClock struct {
-	// The process id that the vector clock belongs to.
-	ID int
-	// Stores the counter value for each process.
-	Counters [numProcesses]int
+// WithScheduler runs the processes one step at a time, in the order the scheduler picks, so that a run can be
+// reproduced exactly. Each send, receive, lock, join and the start of each process is a step. Only works with
+// the channel transport.
+func WithScheduler(s *sched.Scheduler) Option {
+	return func(c *config) {
+		c.scheduler = s
+	}
+}
+
+// WithProcessNames names the processes in the printed timelines, with an empty name for a process without one.
+func WithProcessNames(names []string) Option {
+	return func(c *config) {
+		c.names = names
+	}
+}
+
+// Returns how a process is introduced in the printed timelines, e.g. "Process 1 (bob)" for a named process.
+func processTitle(id int) string {
+	if id < len(runConfig.names) && runConfig.names[id] != "" {
+		return fmt.Sprintf("Process %d (%s)", id, runConfig.names[id])
+	}
+	return fmt.Sprintf("Process %d", id)
+}
+
+// Message represents a message sent between processes.
+type Message struct {
+	// The sender's vector clock at the time of sending.
+	Clock vclock.VectorClock
+	// For broadcast messages, the number of broadcasts from each process the sender had delivered (including this one).
+	// Nil for point-to-point messages.
+	Broadcast *vclock.VectorClock
+	// The sender's matrix clock at the time of sending, when running with matrix clocks.
+	Matrix *vclock.MatrixClock
+	// The sender's Lamport clock at the time of sending, when running with Lamport clocks.
+	Lamport *vclock.LamportClock
+	// The sender's hybrid logical clock at the time of sending, when running with hybrid logical clocks.
+	HLC *vclock.HLC
+	// The simulated true time at which the message was sent.
+	SentAt int64
+	// The sender's interval tree clock stamp at the time of sending, when running with interval tree clocks.
+	// Messages carry an anonymous copy of the stamp, except for the final state a process leaves for a join.
+	ITC *vclock.ITC
+	// The sender's plausible clock at the time of sending, when running with plausible clocks.
+	Plausible *vclock.PlausibleClock
+	// The sender's Bloom clock at the time of sending, when running with Bloom clocks.
+	Bloom *vclock.BloomClock
+	// Set for snapshot markers, which carry no clocks.
+	Marker bool
+	// The snapshot a marker belongs to.
+	Snapshot int
+	// The label of the send or broadcast, shown when the message is received.
+	Payload string
+	// When sent with differential compression, the entries of the sender's vector clock that changed since its
+	// last message to the receiver. Clock is empty until the receiver rebuilds it from them.
+	Entries []vclock.Entry
+	// When injecting faults, the position of the message among those sent over its link, counting from 1.
+	Sequence int
+	// When injecting faults, the ticks the message was delayed by on its way.
+	Delay int64
+	// Set on the empty message a receive gets when it times out, when injecting faults.
+	TimedOut bool
 }
 
 // Stores channels for communicating between processes.
-var channels [numProcesses][numProcesses]chan VectorClock
+var channels [][]chan Message
+
+// Stores a channel for each process to leave its final state on when it finishes, for a process that joins it.
+var exits []chan Message
+
+// Stores the parsed events of every process, used to start processes when they are forked.
+var scriptEvents [][]Event
 
 // Records all clock values for all processes.
-var allClockValues [numProcesses][][numProcesses]int
+var allClockValues [][][]int
 
-// Increments the process's counter.
-func (clock *VectorClock) inc() {
-	clock.Counters[clock.ID]++
-}
+// Records all matrix clock values for all processes, when running with matrix clocks.
+var allMatrixValues [][]*vclock.MatrixClock
+
+// Records all Lamport clock values for all processes, when running with Lamport clocks.
+var allLamportValues [][]*vclock.LamportClock
+
+// Records all hybrid logical clock values for all processes, when running with hybrid logical clocks.
+var allHLCValues [][]*vclock.HLC
+
+// Records the physical clock reading at every event for all processes, when running with hybrid logical clocks.
+var allPhysicalValues [][]int64
+
+// Records all interval tree clock stamps for all processes, when running with interval tree clocks.
+var allITCValues [][]*vclock.ITC
+
+// Records all plausible clock values for all processes, when running with plausible clocks.
+var allPlausibleValues [][]*vclock.PlausibleClock
 
-// Increments the process's counter and sends a message to another process.
-func (clock *VectorClock) send(dest int) {
-	clock.inc()
-	channels[clock.ID][dest] <- *clock
+// Records all Bloom clock values for all processes, when running with Bloom clocks.
+var allBloomValues [][]*vclock.BloomClock
+
+// Records the event behind each clock value in allClockValues. Besides the events in the input, a timeline
+// can contain deliveries of broadcasts ('D' events), which happen when a buffered broadcast becomes deliverable.
+var allTimelineEvents [][]Event
+
+// State kept by a node while it runs its events.
+type nodeState struct {
+	// The node's vector clock.
+	clock *vclock.VectorClock
+	// Counts the broadcasts from each process that have been delivered, used for causal delivery.
+	delivered *vclock.VectorClock
+	// Broadcasts that have been received but cannot be delivered yet.
+	pending []Message
+	// Records all clock values for the process.
+	clockValues [][]int
+	// Records the event behind each clock value.
+	timelineEvents []Event
+	// The node's matrix clock, nil unless running with matrix clocks.
+	matrix *vclock.MatrixClock
+	// Records all matrix clock values for the process.
+	matrixValues []*vclock.MatrixClock
+	// The node's Lamport clock, nil unless running with Lamport clocks.
+	lamport *vclock.LamportClock
+	// Records all Lamport clock values for the process.
+	lamportValues []*vclock.LamportClock
+	// The simulated true time at the node, which advances by one tick per event and by the length of each wait.
+	trueTime int64
+	// The node's hybrid logical clock, nil unless running with hybrid logical clocks.
+	hlc *vclock.HLC
+	// The node's simulated physical clock, used with hybrid logical clocks.
+	physicalClock PhysicalClock
+	// Records all hybrid logical clock values for the process.
+	hlcValues []*vclock.HLC
+	// Records the physical clock reading at every event for the process.
+	physicalValues []int64
+	// The node's interval tree clock stamp, nil unless running with interval tree clocks.
+	itc *vclock.ITC
+	// Records all interval tree clock stamps for the process.
+	itcValues []*vclock.ITC
+	// The node's plausible clock, nil unless running with plausible clocks.
+	plausible *vclock.PlausibleClock
+	// Records all plausible clock values for the process.
+	plausibleValues []*vclock.PlausibleClock
+	// The node's Bloom clock, nil unless running with Bloom clocks.
+	bloom *vclock.BloomClock
+	// Records all Bloom clock values for the process.
+	bloomValues []*vclock.BloomClock
+	// The node's state for each snapshot in the run.
+	snapshots []*localSnapshot
+	// The bookkeeping for sending the node's vector clock with differential compression, nil unless running with it.
+	differential *vclock.Differential
+	// What the vector clocks on the node's messages cost, when running with differential compression.
+	wireStats WireStats
 }
 
-// Receives a message from another process and updates the vector clock.
-func (clock *VectorClock) recv(source int) {
-	recvClock := <-channels[source][clock.ID]
-	for i := 0; i < numProcesses; i++ {
-		if i == clock.ID {
-			clock.inc()
-		} else if clock.Counters[i] <= recvClock.Counters[i] {
-			clock.Counters[i] = recvClock.Counters[i]
-			if i == recvClock.ID {
-				clock.Counters[i]++
-			}
+// Creates the state for node id.
+func newNodeState(id int) *nodeState {
+	state := &nodeState{
+		clock:          vclock.New(id, numProcesses),
+		delivered:      vclock.New(id, numProcesses),
+		clockValues:    [][]int{},
+		timelineEvents: []Event{},
+	}
+	if runConfig.matrix {
+		state.matrix = vclock.NewMatrix(id, numProcesses)
+		state.matrixValues = []*vclock.MatrixClock{}
+	}
+	if runConfig.lamport {
+		state.lamport = vclock.NewLamport(id)
+		state.lamportValues = []*vclock.LamportClock{}
+	}
+	if runConfig.hybrid {
+		state.hlc = vclock.NewHLC(id)
+		if id < len(runConfig.physicalClocks) {
+			state.physicalClock = runConfig.physicalClocks[id]
 		}
+		state.hlcValues = []*vclock.HLC{}
+		state.physicalValues = []int64{}
+	}
+	if runConfig.itc {
+		// Processes that are forked get their stamp from the process that forks them.
+		state.itc = itcSeeds[id]
+		state.itcValues = []*vclock.ITC{}
+	}
+	if runConfig.plausible > 0 {
+		state.plausible = vclock.NewPlausible(id, runConfig.plausible)
+		state.plausibleValues = []*vclock.PlausibleClock{}
+	}
+	if runConfig.bloomCells > 0 {
+		state.bloom = vclock.NewBloom(id, runConfig.bloomCells, runConfig.bloomHashes)
+		state.bloomValues = []*vclock.BloomClock{}
+	}
+	if runConfig.differential {
+		state.differential = vclock.NewDifferential(state.clock, numProcesses)
+	}
+	state.snapshots = make([]*localSnapshot, len(allSnapshots))
+	for s := range state.snapshots {
+		state.snapshots[s] = &localSnapshot{markers: make([]bool, numProcesses)}
+	}
+	return state
+}
+
+// Increments the node's counter in each of its counter based clocks.
+func (state *nodeState) tickCounters() {
+	state.clock.Tick()
+	if state.matrix != nil {
+		state.matrix.Tick()
+	}
+	if state.lamport != nil {
+		state.lamport.Tick()
+	}
+	if state.itc != nil {
+		state.itc = state.itc.Event()
+	}
+	if state.plausible != nil {
+		state.plausible.Tick()
+	}
+	if state.bloom != nil {
+		state.bloom.Tick()
+	}
+	if runConfig.differential {
+		state.differential.Record()
+	}
+}
+
+// Updates the node's clocks for a local, send or broadcast event, or the receipt of a broadcast.
+func (state *nodeState) tick() {
+	state.trueTime++
+	state.tickCounters()
+	if state.hlc != nil {
+		state.hlc.Now(state.physicalClock.Read(state.trueTime))
 	}
 }
 
+// Returns a message stamped with copies of the node's clocks.
+func (state *nodeState) message() Message {
+	msg := Message{Clock: *state.clock.Copy(), SentAt: state.trueTime}
+	if state.matrix != nil {
+		msg.Matrix = state.matrix.Copy()
+	}
+	if state.lamport != nil {
+		msg.Lamport = state.lamport.Copy()
+	}
+	if state.hlc != nil {
+		msg.HLC = state.hlc.Copy()
+	}
+	if state.itc != nil {
+		msg.ITC = state.itc.Peek()
+	}
+	if state.plausible != nil {
+		msg.Plausible = state.plausible.Copy()
+	}
+	if state.bloom != nil {
+		msg.Bloom = state.bloom.Copy()
+	}
+	return msg
+}
+
+// Advances the node's true time to when a message arrived, if it arrived after the node's current time.
+func (state *nodeState) arrive(msg Message) {
+	if state.trueTime < msg.SentAt+msg.Delay {
+		state.trueTime = msg.SentAt + msg.Delay
+	}
+}
+
+// Merges the clocks carried by a message into the node's clocks and then ticks them.
+func (state *nodeState) merge(msg Message) {
+	state.arrive(msg)
+	state.trueTime++
+	state.clock.Merge(&msg.Clock)
+	if state.matrix != nil {
+		state.matrix.Merge(msg.Matrix)
+	}
+	if state.lamport != nil {
+		state.lamport.Merge(msg.Lamport)
+	}
+	if state.itc != nil {
+		state.itc = state.itc.Join(msg.ITC)
+	}
+	if state.plausible != nil {
+		state.plausible.Merge(msg.Plausible)
+	}
+	if state.bloom != nil {
+		state.bloom.Merge(msg.Bloom)
+	}
+	state.tickCounters()
+	if state.hlc != nil {
+		state.hlc.Update(msg.HLC, state.physicalClock.Read(state.trueTime))
+	}
+}
+
+// Records the current clock values as the result of the given event.
+func (state *nodeState) record(e Event) {
+	state.clockValues = append(state.clockValues, state.clock.Copy().Counters)
+	state.timelineEvents = append(state.timelineEvents, e)
+	if state.matrix != nil {
+		state.matrixValues = append(state.matrixValues, state.matrix.Copy())
+	}
+	if state.lamport != nil {
+		state.lamportValues = append(state.lamportValues, state.lamport.Copy())
+	}
+	if state.hlc != nil {
+		state.hlcValues = append(state.hlcValues, state.hlc.Copy())
+		state.physicalValues = append(state.physicalValues, state.physicalClock.Read(state.trueTime))
+	}
+	if state.itc != nil {
+		state.itcValues = append(state.itcValues, state.itc)
+	}
+	if state.plausible != nil {
+		state.plausibleValues = append(state.plausibleValues, state.plausible.Copy())
+	}
+	if state.bloom != nil {
+		state.bloomValues = append(state.bloomValues, state.bloom.Copy())
+	}
+}
+
+// Sends a message to another process through the transport on behalf of the process's event at the given index.
+// Returns false if the simulation deadlocked, or the transport failed, before the message could be sent.
+func send(id int, dest int, index int, msg Message) bool {
+	return transport.Send(id, dest, index, msg)
+}
+
+// Receives a message from another process through the transport on behalf of the process's event at the given index.
+// Returns false if the simulation deadlocked, or the transport failed, before a message arrived.
+func recv(id int, source int, index int) (Message, bool) {
+	return transport.Receive(id, source, index)
+}
+
 // Populates the 2D channels array with all the channels needed to communicate between processes.
 func createChannels() {
+	channels = make([][]chan Message, numProcesses)
 	for i := 0; i < numProcesses; i++ {
+		channels[i] = make([]chan Message, numProcesses)
 		for j := 0; j < numProcesses; j++ {
-			channels[i][j] = make(chan VectorClock, numProcesses)
+			// Leave room for a marker from every snapshot.
+			channels[i][j] = make(chan Message, numProcesses+len(allSnapshots))
 		}
 	}
+	exits = make([]chan Message, numProcesses)
+	for i := 0; i < numProcesses; i++ {
+		exits[i] = make(chan Message, 1)
+	}
 }
 
-// A goroutine representing a node in the vector clock simulation.
-func node(id int, events []string, wg *sync.WaitGroup) {
-	clock := VectorClock{}
-	clock.ID = id
-	clockValues := [][numProcesses]int{} // records all clock values for the process
-	for _, command := range events {
-		switch command[0] {
+// A goroutine representing a node in the vector clock simulation. A node that was forked by another node
+// starts with the state its parent handed it, otherwise parent is nil.
+func node(id int, parent *Message, wg *sync.WaitGroup) {
+	defer wg.Done()
+	events := scriptEvents[id]
+	state := newNodeState(id)
+	if parent != nil {
+		state.inherit(*parent)
+	}
+	defer func() {
+		allClockValues[id] = state.clockValues
+		allTimelineEvents[id] = state.timelineEvents
+		if state.matrix != nil {
+			allMatrixValues[id] = state.matrixValues
+		}
+		if state.lamport != nil {
+			allLamportValues[id] = state.lamportValues
+		}
+		if state.hlc != nil {
+			allHLCValues[id] = state.hlcValues
+			allPhysicalValues[id] = state.physicalValues
+		}
+		if state.itc != nil {
+			allITCValues[id] = state.itcValues
+		}
+		if state.plausible != nil {
+			allPlausibleValues[id] = state.plausibleValues
+		}
+		if state.bloom != nil {
+			allBloomValues[id] = state.bloomValues
+		}
+		allWireStats[id] = state.wireStats
+	}()
+	if !deadlockMonitor.begin(id) {
+		return
+	}
+	for i, e := range events {
+		switch e.Type {
 		case 'P':
 			// local process
-			clock.inc()
+			state.tick()
+			state.record(e)
 		case 'S':
 			// send to another process
-			dest, err := strconv.Atoi(command[1:])
-			if err != nil || dest >= numProcesses || dest < 0 || dest == clock.ID {
-				// The value after the first character should be an integer in the range [0,numProcesses) and not equal to the current process id.
-				log.Fatal("invalid command: " + command)
+			state.tick()
+			msg := state.message()
+			msg.Payload = e.Label
+			if runConfig.differential {
+				msg = state.compress(e.Peer, msg)
 			}
-			clock.send(dest)
+			if !send(id, e.Peer, i, msg) {
+				return
+			}
+			state.record(e)
 		case 'R':
-			// receive from another process
-			src, err := strconv.Atoi(command[1:])
-			if err != nil || src >= numProcesses || src < 0 || src == clock.ID {
-				// The value after the first character should be an integer in the range [0, numProcesses) and not equal to the current process id.
-				log.Fatal("invalid command: " + command)
+			// receive from another process, recording which process sent the message for a receive from any process
+			source, msg, ok := state.receive(e.Peer, i)
+			if !ok {
+				return
+			}
+			if msg.TimedOut {
+				state.timeOut(e)
+				break
+			}
+			e.Peer = source
+			e.Payload = msg.Payload
+			e.Sequence = msg.Sequence
+			if msg.Broadcast != nil {
+				state.receiveBroadcast(e, msg)
+			} else {
+				state.merge(msg)
+				state.record(e)
+			}
+		case 'W':
+			// wait, only the simulated time passes
+			state.trueTime += int64(e.Ticks)
+		case 'B':
+			// broadcast to every other process
+			if !state.broadcast(e, i) {
+				return
+			}
+		case 'F':
+			// fork a new process
+			state.fork(e, wg)
+		case 'J':
+			// join a process once it has finished, retiring it
+			if !state.join(e, i) {
+				return
+			}
+		case 'r', 'w':
+			// read or write a shared variable
+			state.accessVariable(e)
+		case 'L':
+			// acquire a lock, waiting until it is free
+			if !state.acquire(e, i) {
+				return
+			}
+		case 'U':
+			// release a lock
+			state.release(e, i)
+		case 'C':
+			// initiate a snapshot
+			if !state.initiateSnapshot(i) {
+				return
 			}
-			clock.recv(src)
-		default:
-			// invalid character, should only be P, S, or R
-			log.Fatal("invalid command: " + command)
 		}
-		clockValues = append(clockValues, clock.Counters)
 	}
-	allClockValues[clock.ID] = clockValues
-	wg.Done()
+	if !state.finishSnapshots(len(events)) {
+		return
+	}
+	exit := state.message()
+	exit.ITC = state.itc
+	exits[id] <- exit
+	deadlockMonitor.finish(id)
 }
 
 // Prints the clock values for each process.
 func printClockValues() {
-	for i, clockValues := range allClockValues {
-		fmt.Printf("Process %d timeline: ", i)
-		for j, clockValue := range clockValues {
-			if j != 0 {
-				fmt.Printf("-> ")
-			}
-			fmt.Printf("%v ", clockValue)
+	for i := range allClockValues {
+		printTimeline(i)
+	}
+}
+
+// Prints the clock values for process i.
+func printTimeline(i int) {
+	fmt.Printf("%s timeline: ", processTitle(i))
+	for j, clockValue := range allClockValues[i] {
+		if j != 0 {
+			fmt.Printf("-> ")
+		}
+		fmt.Printf("%s ", vclock.FormatCounters(clockValue))
+		if label := eventLabel(allTimelineEvents[i][j]); label != "" {
+			fmt.Printf("(%s) ", label)
 		}
-		fmt.Printf("\n")
 	}
+	fmt.Printf("\n")
 }
 
-// RunVectorClock runs the vector clock by reading the events from the given event array (used for testing).
-func RunVectorClock(events [numProcesses][]string) {
+// RunVectorClock runs the vector clock by reading the events from the given event lists, one list per process.
+// The number of processes in the simulation is the number of event lists given.
+// An error is returned if an event is invalid, if a send or receive has no match, or if the processes deadlock.
+// After a deadlock, allClockValues holds the clock values recorded up to the point each process got stuck.
+func RunVectorClock(commands [][]string, options ...Option) error {
+	runConfig = config{}
+	for _, option := range options {
+		option(&runConfig)
+	}
+	numProcesses = len(commands)
+	allClockValues = make([][][]int, numProcesses)
+	allTimelineEvents = make([][]Event, numProcesses)
+	allMatrixValues = make([][]*vclock.MatrixClock, numProcesses)
+	allLamportValues = make([][]*vclock.LamportClock, numProcesses)
+	allHLCValues = make([][]*vclock.HLC, numProcesses)
+	allPhysicalValues = make([][]int64, numProcesses)
+	allITCValues = make([][]*vclock.ITC, numProcesses)
+	allPlausibleValues = make([][]*vclock.PlausibleClock, numProcesses)
+	allBloomValues = make([][]*vclock.BloomClock, numProcesses)
+	allWireStats = make([]WireStats, numProcesses)
+	allLockHandoffs = [][2]EventID{}
+	allInjectedFaults = []InjectedFault{}
+	events, err := parseEvents(commands)
+	if err != nil {
+		return err
+	}
+	if runConfig.local != nil {
+		err = validateLocal(events)
+	} else {
+		err = validateEvents(events)
+	}
+	if err != nil {
+		return err
+	}
+	if runConfig.itc && runConfig.transport != nil {
+		return errors.New("interval tree clocks can only be used with the channel transport")
+	}
+	if runConfig.scheduler != nil && runConfig.transport != nil {
+		return errors.New("the scheduler can only be used with the channel transport")
+	}
+	if runConfig.faults != nil && runConfig.transport != nil {
+		return errors.New("faults can only be injected into the channel transport")
+	}
+	if runConfig.faults != nil && runConfig.differential {
+		return errors.New("differential compression needs every message delivered once and in order, so it cannot be used with faults")
+	}
+	if runConfig.faults != nil {
+		if err := validateFaults(*runConfig.faults); err != nil {
+			return err
+		}
+	}
+
+	allSnapshots = newSnapshots(events)
+	locks = newLocks(events)
+	detector = newRaceDetector()
+	transport = runConfig.transport
+	var faults *faultTransport
+	if runConfig.faults != nil {
+		faults = newFaultTransport(*runConfig.faults)
+		transport = faults
+	} else if transport == nil {
+		transport = channelTransport{}
+	}
 	createChannels()
+	scriptEvents = events
+	// Processes that are forked by another process only start when they are forked.
+	initial := []int{}
+	for i, parent := range forkParents(events) {
+		if parent < 0 {
+			initial = append(initial, i)
+		}
+	}
+	if runConfig.local != nil {
+		initial = runConfig.local
+	}
+	itcSeeds = splitSeed(initial)
+	deadlockMonitor = newMonitor(events, initial, runConfig.scheduler)
+	if faults != nil {
+		deadlockMonitor.quiet = faults.quiet
+		if runConfig.scheduler != nil {
+			runConfig.scheduler.OnBlocked(deadlockMonitor.unblock)
+		}
+	}
 	var wg sync.WaitGroup
-	wg.Add(numProcesses)
+	wg.Add(len(initial))
 
-	for i, processEvents := range events {
-		go node(i, processEvents, &wg)
+	for _, i := range initial {
+		go node(i, nil, &wg)
 	}
 	wg.Wait()
+	allRaces = detector.races
+	if faults != nil {
+		allInjectedFaults = faults.injected
+	}
+	err = transport.Close()
+	if runConfig.scheduler != nil && runConfig.scheduler.Err() != nil {
+		return runConfig.scheduler.Err()
+	}
+	if deadlockMonitor.err != nil {
+		return deadlockMonitor.err
+	}
+	return err
+}
+
+// Collects the values of a flag that can be given more than once.
+type queryList []string
+
+func (queries *queryList) String() string {
+	return strings.Join(*queries, ", ")
+}
+
+func (queries *queryList) Set(query string) error {
+	*queries = append(*queries, query)
+	return nil
+}
+
+// Returns the scheduler chosen by the -seed and -schedule flags: one following the schedule in the file if it is
+// given, one picking steps with the seed if it is not 0, and nil otherwise.
+func newScheduler(seed int64, file string) (*sched.Scheduler, error) {
+	if file != "" {
+		text, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		schedule, err := sched.ParseSchedule(string(text))
+		if err != nil {
+			return nil, err
+		}
+		return sched.NewReplay(schedule), nil
+	}
+	if seed != 0 {
+		return sched.New(seed), nil
+	}
+	return nil, nil
 }
 
-// Runs the vector clock by reading the events from stdin.
+// Runs the vector clock by reading a script of events from stdin (see script.go). Each line holds the events for
+// one process, and the processes can be followed by happens-before queries, one per line. With -load, the run is
+// read from a JSON trace instead (see trace.go), and with -diff, it is compared with another run (see diff.go).
+// Given a second trace after the flags, -diff compares the two traces without running anything.
 func main() {
-	scanner := bufio.NewScanner(os.Stdin)
-	createChannels()
-	count := 0
-	var wg sync.WaitGroup
-	wg.Add(numProcesses)
+	format := flag.String("format", "timeline", "output format for the run: timeline, shiviz, dot, svg or json (a trace that -load reads back)")
+	clockType := flag.String("clock", "vector", "type of clock to print the timelines with: vector, matrix, lamport, hlc, itc, plausible or bloom")
+	size := flag.Int("size", 4, "number of entries in a plausible clock, or of counters in a Bloom clock, used with -clock=plausible or -clock=bloom")
+	hashes := flag.Int("hashes", 2, "number of counters each event increments in a Bloom clock, used with -clock=bloom")
+	skews := flag.String("skew", "", "comma separated skew of each process's physical clock, used with -clock=hlc")
+	drifts := flag.String("drift", "", "comma separated drift of each process's physical clock (e.g. 0.1 for 10% fast), used with -clock=hlc")
+	epsilon := flag.Int64("epsilon", 10, "how far ahead of physical time a hybrid logical clock may be, used with -clock=hlc")
+	analyze := flag.Bool("analyze", false, "print the happens-before relation and every pair of concurrent events")
+	var queries queryList
+	flag.Var(&queries, "query", "answer a happens-before query such as \"P0.e2 -> P2.e1?\" or \"shop.e0 -> courier.e1?\" (can be repeated)")
+	lattice := flag.Bool("lattice", false, "print every consistent global state of the run")
+	var predicates queryList
+	flag.Var(&predicates, "predicate", "report whether a predicate such as \"P0.x + P1.y > 5\" or \"shop.x > 0\" possibly and definitely holds, or with -explore that it holds at every event (can be repeated)")
+	id := flag.Int("id", -1, "run only this process, over TCP, with its events read from stdin (needs -peers)")
+	peers := flag.String("peers", "", "comma separated address of every process, e.g. localhost:9000,localhost:9001")
+	coordinator := flag.String("coordinator", "", "address of the coordinator: with -id, the process reports its timeline to it, and without -id, run as the coordinator and print every process's timeline")
+	timeout := flag.Duration("timeout", 10*time.Second, "how long a process running over TCP waits for a peer to accept a connection or for a message")
+	seed := flag.Int64("seed", 0, "run the processes one step at a time in an order picked with this seed, and print the schedule (0 leaves the order to the Go runtime)")
+	schedule := flag.String("schedule", "", "run the processes one step at a time following the schedule in this file, as printed by -seed")
+	explore := flag.Bool("explore", false, "run the events under every order of the steps that could change the outcome, checking the properties and any -predicate at every event")
+	var propertyNames queryList
+	flag.Var(&propertyNames, "property", "a property to check with -explore: monotonic or bounded (can be repeated, defaults to all of them)")
+	limit := flag.Int("limit", 100000, "the most runs to try with -explore, 0 for no limit")
+	load := flag.String("load", "", "load the run from a trace written with -format=json instead of running the events, checking its clocks")
+	diffFile := flag.String("diff", "", "compare an old run, recorded in this trace written with -format=json, with this run, or with the run in the trace given after the flags, printing the events, clocks and happens-before relations that changed")
+	var faultSpecs queryList
+	flag.Var(&faultSpecs, "faults", "inject faults into messages, e.g. \"drop=0.1,duplicate=0.05,delay=0.2,reorder=0.1\" on every link or \"0>1:drop=0.5\" on one link (can be repeated), and compare the run with the run without faults")
+	faultSeed := flag.Int64("fault-seed", 1, "seed for the faults injected with -faults")
+	maxDelay := flag.Int64("max-delay", 5, "the most ticks a message delayed by -faults is delayed by")
+	stuckTimeout := flag.Int64("stuck-timeout", 10, "with -faults, time out every waiting receive once every process is blocked and no delayed message is left, taking each to have waited this many ticks, 0 to wait forever")
+	compress := flag.Bool("compress", false, "send only the vector clock entries that changed since the last message to the same process, and print what it saved")
+	flag.Parse()
+
+	peerList := []string{}
+	if *peers != "" {
+		peerList = strings.Split(*peers, ",")
+	}
+	if (*id >= 0 || *coordinator != "") && len(peerList) == 0 {
+		log.Fatal("-id and -coordinator need the address of every process in -peers")
+	}
+	coordinating := *id < 0 && *coordinator != ""
+	loading := *load != ""
+	if loading && (*id >= 0 || coordinating || *explore || *seed != 0 || *schedule != "" || *compress || *clockType != "vector") {
+		log.Fatal("-load cannot be used with -id, -coordinator, -explore, -seed, -schedule, -compress or clocks other than vector clocks")
+	}
+	faulting := len(faultSpecs) > 0
+	if faulting && (*id >= 0 || coordinating || loading || *explore || *compress) {
+		log.Fatal("-faults cannot be used with -id, -coordinator, -load, -explore or -compress")
+	}
+	faults, err := parseFaults(faultSpecs)
+	if err != nil {
+		log.Fatal(err)
+	}
+	faults.Seed, faults.MaxDelay, faults.Timeout = *faultSeed, *maxDelay, *stuckTimeout
+	if *diffFile != "" && (*id >= 0 || *explore) {
+		log.Fatal("-diff cannot be used with -id or -explore")
+	}
+	if flag.NArg() > 0 {
+		if *diffFile == "" || flag.NArg() > 1 {
+			log.Fatal("The only argument after the flags should be a trace to compare with the one given to -diff")
+		}
+		if coordinating || loading || faulting {
+			log.Fatal("-diff with two traces cannot be used with -coordinator, -load or -faults")
+		}
+		diff, err := diffTraceFiles(*diffFile, flag.Arg(0))
+		if err != nil {
+			log.Fatal(err)
+		}
+		printTraceDiff(diff)
+		return
+	}
 
-	for scanner.Scan() {
-		line := scanner.Text()
-		if line == "" {
-			break
+	events := [][]string{}
+	names := []string{}
+	// The queries after the processes in the script, which already use the numbers of the processes.
+	scriptQueries := []string{}
+	// The number of processes in the script. A run sets numProcesses to the number of processes it runs.
+	processes := 0
+	if !coordinating && !loading {
+		script, n, err := parseScript(os.Stdin, *id, len(peerList))
+		if err != nil {
+			log.Fatal(err)
+		}
+		events = script.Commands
+		names = script.Names
+		scriptQueries = script.Queries
+		processes = n
+	}
+	if processes == 0 && !coordinating && !loading {
+		log.Fatal("The input should contain at least one line of events")
+	}
+	writers := map[string]func(io.Writer) error{
+		"shiviz": writeShiViz,
+		"dot":    writeDot,
+		"svg":    writeSVG,
+		"json":   writeTrace,
+	}
+	if _, ok := writers[*format]; !ok && *format != "timeline" {
+		log.Fatal("unknown format: " + *format)
+	}
+	options := []Option{WithProcessNames(names)}
+	switch *clockType {
+	case "vector":
+	case "matrix":
+		options = append(options, WithMatrixClocks())
+	case "lamport":
+		options = append(options, WithLamportClocks())
+	case "hlc":
+		physicalClocks, err := parsePhysicalClocks(*skews, *drifts)
+		if err != nil {
+			log.Fatal(err)
+		}
+		options = append(options, WithHybridClocks(physicalClocks))
+	case "itc":
+		options = append(options, WithIntervalTreeClocks())
+	case "plausible":
+		if *size <= 0 {
+			log.Fatal("-size must be positive")
 		}
-		go node(count, strings.Split(line, " "), &wg)
-		count++
-		if count > numProcesses {
-			// The file should only contain lines equal to the number of processes.
-			log.Fatal("The file should only contain", numProcesses, "lines")
+		options = append(options, WithPlausibleClocks(*size))
+	case "bloom":
+		if *size <= 0 || *hashes <= 0 {
+			log.Fatal("-size and -hashes must be positive")
 		}
+		options = append(options, WithBloomClocks(*size, *hashes))
+	default:
+		log.Fatal("unknown clock type: " + *clockType)
 	}
-	if err := scanner.Err(); err != nil {
+	if *compress {
+		options = append(options, WithDifferentialClocks())
+	}
+	if *explore {
+		if *id >= 0 || coordinating || *seed != 0 || *schedule != "" {
+			log.Fatal("-explore cannot be used with -id, -coordinator, -seed or -schedule")
+		}
+		checks, err := lookupProperties(propertyNames)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, text := range predicates {
+			predicate, err := parsePredicate(text, len(events), processNumbers(names))
+			if err != nil {
+				log.Fatal(err)
+			}
+			checks = append(checks, predicateProperty(predicate))
+		}
+		exploration, err := Explore(events, checks, *limit, options...)
+		if err != nil {
+			log.Fatal(err)
+		}
+		printExploration(exploration, checks)
+		return
+	}
+	scheduler, err := newScheduler(*seed, *schedule)
+	if err != nil {
 		log.Fatal(err)
 	}
-	wg.Wait()
-	printClockValues()
+	if scheduler != nil {
+		options = append(options, WithScheduler(scheduler))
+	}
+	if *id >= 0 {
+		if err := runNode(*id, peerList, events[0], *coordinator, *timeout, options); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	var comparison *FaultComparison
+	if loading {
+		err = loadTraceFile(*load)
+	} else if faulting {
+		comparison, err = compareFaults(events, faults, options...)
+	} else if coordinating {
+		err = RunCoordinator(*coordinator, len(peerList))
+	} else {
+		err = RunVectorClock(events, options...)
+	}
+	if scheduler != nil {
+		fmt.Fprintln(os.Stderr, sched.FormatSchedule(scheduler.Schedule()))
+	}
+	if *format == "timeline" && *clockType == "matrix" {
+		printMatrixValues()
+	} else if *format == "timeline" && *clockType == "lamport" {
+		printLamportValues()
+	} else if *format == "timeline" && *clockType == "hlc" {
+		printHLCValues(*epsilon)
+	} else if *format == "timeline" && *clockType == "itc" {
+		printITCValues()
+	} else if *format == "timeline" && *clockType == "plausible" {
+		printPlausibleValues()
+	} else if *format == "timeline" && *clockType == "bloom" {
+		printBloomValues()
+	} else if *format == "timeline" {
+		printClockValues()
+	}
+	if *format == "timeline" {
+		printSnapshots()
+		if !loading {
+			// A trace does not record the order the shared variables were accessed in.
+			printRaces()
+		}
+		if *compress && !coordinating {
+			printWireStats()
+		}
+		if comparison != nil {
+			printFaultComparison(comparison)
+		}
+	} else if err := writers[*format](os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *analyze {
+		printAnalysis()
+	}
+	// The queries given with -query can use the names of the processes, like the queries in the script.
+	numbers := processNumbers(runConfig.names)
+	for i, query := range queries {
+		queries[i] = resolveQuery(query, numbers)
+	}
+	for _, query := range append(queries, scriptQueries...) {
+		answer, err := answerQuery(query)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(answer)
+	}
+	if *lattice {
+		printLattice()
+	}
+	for _, text := range predicates {
+		predicate, err := parsePredicate(text, len(allClockValues), processNumbers(runConfig.names))
+		if err != nil {
+			log.Fatal(err)
+		}
+		printPredicate(predicate)
+	}
+	if *diffFile != "" {
+		diff, err := diffTraceFile(*diffFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		printTraceDiff(diff)
+	}
 }
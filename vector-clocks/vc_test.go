@@ -1,7 +1,10 @@
 package main
 
 import (
+	"reflect"
 	"testing"
+
+	"github.com/kulvirs/Concurrency-A2/sched"
 )
 
 // Verifies that the event referenced by indices 0,1 comes before the event referenced by indices 2,3 by comparing the values of their vector clocks.
@@ -28,7 +31,7 @@ func TestVectorClockSingleMessage(t *testing.T) {
 	p0Events := []string{"P0", "S1", "P0"}
 	p1Events := []string{"P1", "P1", "R0", "P1"}
 	p2Events := []string{"P2", "P2"}
-	events := [3][]string{p0Events, p1Events, p2Events}
+	events := [][]string{p0Events, p1Events, p2Events}
 
 	// Each expected order is represented with an array of 4 integers
 	// Example: {a, b, c, d} symbolizes that Process a Event b should come before Process c Event d
@@ -49,7 +52,7 @@ func TestVectorClockTwoMessages(t *testing.T) {
 	p0Events := []string{"S1", "P0", "P0"}
 	p1Events := []string{"P1", "R0", "S2"}
 	p2Events := []string{"P2", "R1", "P2"}
-	events := [3][]string{p0Events, p1Events, p2Events}
+	events := [][]string{p0Events, p1Events, p2Events}
 
 	// Process 0 Event 0 happens before Process 1 Event 1.
 	// Process 1 Event 2 happens before Process 2 Event 1.
@@ -70,7 +73,7 @@ func TestVectorClockSingleMessageAllPairs(t *testing.T) {
 	p0Events := []string{"R1", "S2"}
 	p1Events := []string{"S0", "P1", "R2"}
 	p2Events := []string{"S1", "P2", "R0"}
-	events := [3][]string{p0Events, p1Events, p2Events}
+	events := [][]string{p0Events, p1Events, p2Events}
 
 	// Process 1 Event 0 happens before Process 0 Event 0.
 	// Process 0 Event 1 happens before Process 2 Event 2.
@@ -92,7 +95,7 @@ func TestVectorClockMessageAllPairsBothDirections(t *testing.T) {
 	p0Events := []string{"S1", "R1", "R2", "S2"}
 	p1Events := []string{"S0", "R0", "S2", "R2"}
 	p2Events := []string{"S0", "R1", "S1", "R0"}
-	events := [3][]string{p0Events, p1Events, p2Events}
+	events := [][]string{p0Events, p1Events, p2Events}
 
 	// Process 0 Event 0 happens before Process 1 Event 1.
 	// Process 1 Event 0 happens before Process 0 Event 1.
@@ -112,3 +115,90 @@ func TestVectorClockMessageAllPairsBothDirections(t *testing.T) {
 		}
 	}
 }
+
+// Tests the vector clock implementation maintains the correct ordering with more than three processes.
+func TestVectorClockFiveProcesses(t *testing.T) {
+	p0Events := []string{"S1", "P0"}
+	p1Events := []string{"R0", "S2"}
+	p2Events := []string{"R1", "S3"}
+	p3Events := []string{"R2", "S4"}
+	p4Events := []string{"P4", "R3"}
+	events := [][]string{p0Events, p1Events, p2Events, p3Events, p4Events}
+
+	// The message is passed along the chain 0 -> 1 -> 2 -> 3 -> 4.
+	// Transitively, Process 0 Event 0 happens before Process 4 Event 1.
+	expectedOrders := [][4]int{[4]int{0, 0, 1, 0}, [4]int{1, 1, 2, 0}, [4]int{2, 1, 3, 0}, [4]int{3, 1, 4, 1}, [4]int{0, 0, 4, 1}}
+
+	RunVectorClock(events)
+	if len(allClockValues[4][1]) != 5 {
+		t.Errorf("Expected vector clocks with 5 entries, but got %v", allClockValues[4][1])
+	}
+	// Check for correct ordering.
+	for _, expectedOrder := range expectedOrders {
+		if verifyOrder(expectedOrder) == false {
+			t.Errorf("Expected Process %d Event %d to come before Process %d Event %d, but it did not.", expectedOrder[0], expectedOrder[1], expectedOrder[2], expectedOrder[3])
+		}
+	}
+}
+
+// Events whose clocks depend on the order the processes take the lock in.
+var lockEvents = [][]string{{"L", "P", "U", "S1"}, {"L", "P", "U", "R0"}, {"P", "L", "U"}}
+
+// Tests that two runs with the same seed take the same steps and produce the same timelines.
+func TestSchedulerSameSeed(t *testing.T) {
+	first := sched.New(42)
+	if err := RunVectorClock(lockEvents, WithScheduler(first)); err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	clocks := allClockValues
+	second := sched.New(42)
+	if err := RunVectorClock(lockEvents, WithScheduler(second)); err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	if !reflect.DeepEqual(first.Schedule(), second.Schedule()) {
+		t.Errorf("Expected schedule %v but got %v", first.Schedule(), second.Schedule())
+	}
+	if !reflect.DeepEqual(clocks, allClockValues) {
+		t.Errorf("Expected clocks %v but got %v", clocks, allClockValues)
+	}
+}
+
+// Tests that following the schedule printed by a run reproduces its timelines for several seeds.
+func TestSchedulerReplay(t *testing.T) {
+	for seed := int64(1); seed <= 10; seed++ {
+		s := sched.New(seed)
+		if err := RunVectorClock(lockEvents, WithScheduler(s)); err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+		clocks := allClockValues
+		schedule, err := sched.ParseSchedule(sched.FormatSchedule(s.Schedule()))
+		if err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+		if err := RunVectorClock(lockEvents, WithScheduler(sched.NewReplay(schedule))); err != nil {
+			t.Fatalf("Expected no error replaying seed %d but got %v", seed, err)
+		}
+		if !reflect.DeepEqual(clocks, allClockValues) {
+			t.Errorf("Expected seed %d to replay with clocks %v but got %v", seed, clocks, allClockValues)
+		}
+	}
+}
+
+// Tests that a deadlock is still detected when the processes run under a scheduler.
+func TestSchedulerDeadlock(t *testing.T) {
+	events := [][]string{{"R1", "S1"}, {"R0", "S0"}}
+	err := RunVectorClock(events, WithScheduler(sched.New(1)))
+	if _, ok := err.(*DeadlockError); !ok {
+		t.Errorf("Expected a deadlock error but got %v", err)
+	}
+}
+
+// Tests that a schedule that cannot be followed is reported.
+func TestSchedulerInvalidReplay(t *testing.T) {
+	// Process 1 cannot receive before process 0 has sent.
+	events := [][]string{{"S1"}, {"R0"}}
+	err := RunVectorClock(events, WithScheduler(sched.NewReplay([]int{0, 1, 1, 0})))
+	if err == nil || err.Error() != "step 2 of the schedule: goroutine 1 cannot take a step" {
+		t.Errorf("Expected the schedule to fail at step 2 but got %v", err)
+	}
+}
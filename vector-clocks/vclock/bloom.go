@@ -0,0 +1,65 @@
+package vclock
+
+import (
+	"fmt"
+	"hash/fnv"
+)
+
+// BloomClock represents a Bloom clock, a fixed number of counters that works like a counting Bloom filter of the
+// events a process has heard of. Each event increments the counters picked by hashing the event k times, and a
+// merge takes the max of each counter. If an event happened before another, every counter of the first is at
+// most the corresponding counter of the second, but the counters can also line up by chance for concurrent events,
+// so Bloom clocks report happened-before with false positives.
+type BloomClock struct {
+	// The process id that the clock belongs to.
+	ID int
+	// The number of events the owning process has had, which identifies its next event.
+	Events int
+	// The number of counters each event increments.
+	Hashes int
+	// Stores the counters.
+	Cells []int
+}
+
+// NewBloom returns a Bloom clock for process id with m counters, each event incrementing k of them.
+func NewBloom(id int, m int, k int) *BloomClock {
+	return &BloomClock{id, 0, k, make([]int, m)}
+}
+
+// Tick adds the owning process's next event to the clock, incrementing the counters its hashes pick.
+func (clock *BloomClock) Tick() {
+	clock.Events++
+	for i := 0; i < clock.Hashes; i++ {
+		hash := fnv.New64a()
+		fmt.Fprintf(hash, "%d/%d/%d", clock.ID, clock.Events, i)
+		clock.Cells[hash.Sum64()%uint64(len(clock.Cells))]++
+	}
+}
+
+// Merge sets every counter to the max of its own value and the corresponding value in other.
+// Like VectorClock.Merge, it does not tick the clock.
+func (clock *BloomClock) Merge(other *BloomClock) {
+	for i, cell := range other.Cells {
+		if clock.Cells[i] < cell {
+			clock.Cells[i] = cell
+		}
+	}
+}
+
+// Compare reports how clock relates to other in the same way as VectorClock.Compare. Before and After may be
+// reported for concurrent events, but never Concurrent for ordered ones.
+func (clock *BloomClock) Compare(other *BloomClock) Ordering {
+	return CompareCounters(clock.Cells, other.Cells)
+}
+
+// Copy returns a copy of the Bloom clock that does not share its counters with the original.
+func (clock *BloomClock) Copy() *BloomClock {
+	cells := make([]int, len(clock.Cells))
+	copy(cells, clock.Cells)
+	return &BloomClock{clock.ID, clock.Events, clock.Hashes, cells}
+}
+
+// String returns the counters of the clock in the form [a b c].
+func (clock *BloomClock) String() string {
+	return FormatCounters(clock.Cells)
+}
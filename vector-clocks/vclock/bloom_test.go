@@ -0,0 +1,46 @@
+package vclock
+
+import (
+	"testing"
+)
+
+// Tests that each event increments as many counters as there are hashes, and that the same event always picks
+// the same counters.
+func TestBloomTick(t *testing.T) {
+	a, b := NewBloom(0, 16, 3), NewBloom(0, 16, 3)
+	a.Tick()
+	b.Tick()
+	total := 0
+	for _, cell := range a.Cells {
+		total += cell
+	}
+	if total != 3 || a.String() != b.String() {
+		t.Errorf("Expected the same 3 increments in both clocks but got %v and %v", a, b)
+	}
+	c := NewBloom(1, 16, 3)
+	c.Tick()
+	if c.String() == a.String() {
+		t.Errorf("Expected the events of different processes to pick different counters but both got %v", a)
+	}
+}
+
+// Tests that a message orders the send before the receive, and that a Bloom clock with a single counter reports
+// every pair of events as ordered.
+func TestBloomOrder(t *testing.T) {
+	a, b := NewBloom(0, 8, 2), NewBloom(1, 8, 2)
+	a.Tick()
+	message := a.Copy()
+	b.Tick()
+	b.Merge(message)
+	b.Tick()
+	if message.Compare(b) != Before || b.Compare(message) != After {
+		t.Errorf("Expected the send %v to come before the receive %v", message, b)
+	}
+	x, y := NewBloom(0, 1, 1), NewBloom(1, 1, 1)
+	x.Tick()
+	y.Tick()
+	y.Tick()
+	if x.Compare(y) != Before {
+		t.Errorf("Expected concurrent events to look ordered with a single counter but got %v", x.Compare(y))
+	}
+}
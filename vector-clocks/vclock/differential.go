@@ -0,0 +1,97 @@
+package vclock
+
+import (
+	"encoding/binary"
+)
+
+// Entry is a single counter of a vector clock, sent on its own by differential compression.
+type Entry struct {
+	// The process the counter belongs to.
+	Process int
+	// The value of the counter.
+	Counter int
+}
+
+// Differential sends a vector clock with the Singhal-Kshemkalyani differential technique: a message only carries
+// the counters that changed since the owner's last message to the same process. The owner remembers its own
+// counter when it last sent to each process and when each counter last changed. Messages between each pair of
+// processes must be delivered in the order they were sent, so the receiver can rebuild the sender's whole clock
+// from the last clock it rebuilt from that sender.
+type Differential struct {
+	// The clock being sent.
+	clock *VectorClock
+	// The owner's counter when it last sent to each process, or -1 if it has not sent to it yet.
+	lastSent []int
+	// The owner's counter when each counter last changed.
+	lastUpdate []int
+	// The counters when Record was last called.
+	recorded []int
+	// The last clock rebuilt from the messages of each process, nil before the first one.
+	received [][]int
+}
+
+// NewDifferential returns the bookkeeping for sending clock, which belongs to a process in a system of n processes.
+func NewDifferential(clock *VectorClock, n int) *Differential {
+	d := &Differential{clock, make([]int, n), make([]int, n), make([]int, n), make([][]int, n)}
+	for j := range d.lastSent {
+		d.lastSent[j] = -1
+	}
+	d.Record()
+	return d
+}
+
+// Record notes the counters that changed since it was last called, as changed at the owner's current counter.
+// It should be called after every tick, and after every merge once the clock has been ticked.
+func (d *Differential) Record() {
+	for k, counter := range d.clock.Counters {
+		if counter != d.recorded[k] {
+			d.recorded[k] = counter
+			d.lastUpdate[k] = d.clock.Counters[d.clock.ID]
+		}
+	}
+}
+
+// Entries returns the counters to send to process dest: every counter that changed since the owner's last
+// message to dest, leaving out counters that are still 0. The message is recorded as sent.
+func (d *Differential) Entries(dest int) []Entry {
+	entries := []Entry{}
+	for k, counter := range d.clock.Counters {
+		if counter > 0 && d.lastUpdate[k] > d.lastSent[dest] {
+			entries = append(entries, Entry{k, counter})
+		}
+	}
+	d.lastSent[dest] = d.clock.Counters[d.clock.ID]
+	return entries
+}
+
+// Rebuild returns the whole clock of process source from the entries of its next message to the owner.
+func (d *Differential) Rebuild(source int, entries []Entry) *VectorClock {
+	counters := make([]int, len(d.clock.Counters))
+	copy(counters, d.received[source])
+	for _, e := range entries {
+		counters[e.Process] = e.Counter
+	}
+	d.received[source] = counters
+	rebuilt := make([]int, len(counters))
+	copy(rebuilt, counters)
+	return &VectorClock{source, rebuilt}
+}
+
+// EncodedSize returns the number of bytes the counters take with each one written as a varint.
+func EncodedSize(counters []int) int {
+	size := 0
+	buffer := make([]byte, binary.MaxVarintLen64)
+	for _, counter := range counters {
+		size += binary.PutUvarint(buffer, uint64(counter))
+	}
+	return size
+}
+
+// EncodedEntriesSize returns the number of bytes the entries take with each process and counter written as a varint.
+func EncodedEntriesSize(entries []Entry) int {
+	size := 0
+	for _, e := range entries {
+		size += EncodedSize([]int{e.Process, e.Counter})
+	}
+	return size
+}
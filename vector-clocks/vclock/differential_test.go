@@ -0,0 +1,50 @@
+package vclock
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Tests that a message only carries the counters that changed since the last message to the same process, and
+// that the receiver rebuilds the sender's whole clock from them.
+func TestDifferential(t *testing.T) {
+	a, b := New(0, 4), New(1, 4)
+	sender, receiver := NewDifferential(a, 4), NewDifferential(b, 4)
+	send := func() []Entry {
+		a.Tick()
+		sender.Record()
+		entries := sender.Entries(1)
+		if rebuilt := receiver.Rebuild(0, entries); !reflect.DeepEqual(rebuilt, a) {
+			t.Errorf("Expected the receiver to rebuild %v but got %v", a, rebuilt)
+		}
+		return entries
+	}
+
+	if entries := send(); !reflect.DeepEqual(entries, []Entry{{0, 1}}) {
+		t.Errorf("Expected only the sender's own counter but got %v", entries)
+	}
+	// A message from process 3 changes one counter.
+	a.Merge(&VectorClock{3, []int{0, 0, 0, 5}})
+	a.Tick()
+	sender.Record()
+	if entries := send(); !reflect.DeepEqual(entries, []Entry{{0, 3}, {3, 5}}) {
+		t.Errorf("Expected the sender's own counter and the merged one but got %v", entries)
+	}
+	if entries := send(); !reflect.DeepEqual(entries, []Entry{{0, 4}}) {
+		t.Errorf("Expected only the sender's own counter after nothing else changed but got %v", entries)
+	}
+	// The first message to another process carries every counter that is not 0.
+	if entries := sender.Entries(2); !reflect.DeepEqual(entries, []Entry{{0, 4}, {3, 5}}) {
+		t.Errorf("Expected every counter that is not 0 but got %v", entries)
+	}
+}
+
+// Tests the size of counters and entries written as varints.
+func TestEncodedSize(t *testing.T) {
+	if size := EncodedSize([]int{0, 127, 128, 300}); size != 6 {
+		t.Errorf("Expected 6 bytes but got %d", size)
+	}
+	if size := EncodedEntriesSize([]Entry{{1, 200}, {130, 1}}); size != 6 {
+		t.Errorf("Expected 6 bytes but got %d", size)
+	}
+}
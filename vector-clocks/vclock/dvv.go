@@ -0,0 +1,114 @@
+package vclock
+
+import (
+	"fmt"
+)
+
+// Dot identifies a single write by the replica that made it and that replica's counter at the time.
+type Dot struct {
+	Replica int
+	Counter int
+}
+
+// String returns the dot in the form (replica,counter).
+func (dot Dot) String() string {
+	return fmt.Sprintf("(%d,%d)", dot.Replica, dot.Counter)
+}
+
+// DVV represents a dotted version vector, the clock of a single version of a value in a replicated store.
+// The dot identifies the write that created the version, and the context is the version vector of every write
+// the writer had seen, which the new version replaces. Unlike a plain version vector, the dot keeps versions
+// written concurrently at the same replica apart.
+type DVV struct {
+	Dot     Dot
+	Context []int
+}
+
+// Covers reports whether the version vector v includes the write identified by dot.
+func Covers(v []int, dot Dot) bool {
+	return dot.Replica < len(v) && dot.Counter <= v[dot.Replica]
+}
+
+// History returns the version vector of every write the version includes: its context plus its own dot.
+func (clock DVV) History() []int {
+	history := make([]int, len(clock.Context))
+	copy(history, clock.Context)
+	for len(history) <= clock.Dot.Replica {
+		history = append(history, 0)
+	}
+	if history[clock.Dot.Replica] < clock.Dot.Counter {
+		history[clock.Dot.Replica] = clock.Dot.Counter
+	}
+	return history
+}
+
+// Obsoletes reports whether the version replaces other, because it was written after seeing other's write.
+// Only the context counts, the dot is a single write and says nothing about earlier writes at its replica.
+func (clock DVV) Obsoletes(other DVV) bool {
+	return clock.Dot != other.Dot && Covers(clock.Context, other.Dot)
+}
+
+// String returns the version's clock in the form (replica,counter)[context].
+func (clock DVV) String() string {
+	return clock.Dot.String() + FormatCounters(clock.Context)
+}
+
+// Context returns the version vector of every write in a set of sibling versions. A client that reads the
+// siblings and writes back with this context replaces all of them.
+func Context(siblings []DVV) []int {
+	context := []int{}
+	for _, sibling := range siblings {
+		for i, counter := range sibling.History() {
+			for len(context) <= i {
+				context = append(context, 0)
+			}
+			if context[i] < counter {
+				context[i] = counter
+			}
+		}
+	}
+	return context
+}
+
+// Write returns the clock of a new version written at replica with the given context, when the replica holds
+// the given siblings. The new version's dot is the next counter for the replica.
+func Write(siblings []DVV, context []int, replica int) DVV {
+	counter := 0
+	for _, history := range append([][]int{context}, historiesOf(siblings)...) {
+		if replica < len(history) && history[replica] > counter {
+			counter = history[replica]
+		}
+	}
+	ctx := make([]int, len(context))
+	copy(ctx, context)
+	return DVV{Dot{replica, counter + 1}, ctx}
+}
+
+// Returns the history of every sibling.
+func historiesOf(siblings []DVV) [][]int {
+	histories := make([][]int, len(siblings))
+	for i, sibling := range siblings {
+		histories[i] = sibling.History()
+	}
+	return histories
+}
+
+// Sync returns the indexes of the versions to keep from a combined set of siblings: duplicates of the same
+// write are kept once, and versions obsoleted by another version are dropped. Whatever is left was written
+// concurrently and is a conflict.
+func Sync(siblings []DVV) []int {
+	keep := []int{}
+	for i, sibling := range siblings {
+		obsolete := false
+		for j, other := range siblings {
+			if other.Obsoletes(sibling) || (j < i && other.Dot == sibling.Dot) {
+				obsolete = true
+				break
+			}
+		}
+		if !obsolete {
+			keep = append(keep, i)
+		}
+	}
+	return keep
+}
@@ -0,0 +1,44 @@
+package vclock
+
+import (
+	"testing"
+)
+
+// Tests that concurrent writes at the same replica are kept as siblings, and a write with their context replaces both.
+func TestDVVSameReplica(t *testing.T) {
+	// Two clients write blind (with an empty context) to replica 0.
+	first := Write(nil, nil, 0)
+	second := Write([]DVV{first}, nil, 0)
+	if first.String() != "(0,1)[]" || second.String() != "(0,2)[]" {
+		t.Errorf("Expected clocks (0,1)[] and (0,2)[], but got %v and %v", first, second)
+	}
+	siblings := []DVV{first, second}
+	if keep := Sync(siblings); len(keep) != 2 {
+		t.Errorf("Expected both blind writes to be kept as siblings, but kept %v", keep)
+	}
+
+	// A client reads both siblings and writes back with their context.
+	resolved := Write(siblings, Context(siblings), 0)
+	if resolved.String() != "(0,3)[2]" {
+		t.Errorf("Expected the resolving write to be (0,3)[2], but got %v", resolved)
+	}
+	if keep := Sync(append(siblings, resolved)); len(keep) != 1 || keep[0] != 2 {
+		t.Errorf("Expected only the resolving write to be kept, but kept %v", keep)
+	}
+}
+
+// Tests that syncing replicas keeps duplicates once and drops versions that were overwritten.
+func TestDVVSync(t *testing.T) {
+	a := Write(nil, nil, 0)
+	b := Write(nil, []int{1}, 1)
+	c := Write(nil, nil, 2)
+
+	// b was written after seeing a, and c is concurrent with both.
+	keep := Sync([]DVV{a, b, a, c})
+	if len(keep) != 2 || keep[0] != 1 || keep[1] != 3 {
+		t.Errorf("Expected to keep b and c, but kept %v", keep)
+	}
+	if !b.Obsoletes(a) || a.Obsoletes(b) || c.Obsoletes(a) {
+		t.Errorf("Expected only b to obsolete a, but it did not.")
+	}
+}
@@ -0,0 +1,78 @@
+package vclock
+
+import (
+	"fmt"
+)
+
+// HLC represents a hybrid logical clock, which combines the physical time of a process with a logical counter.
+// The physical component stays close to physical time (it is the largest physical time the process has heard of),
+// while the logical component orders events that happen at the same physical time.
+type HLC struct {
+	// The process id that the clock belongs to.
+	ID int
+	// The physical component, the largest physical time heard of.
+	Physical int64
+	// The logical component, used to order events with the same physical component.
+	Logical int
+}
+
+// NewHLC returns a hybrid logical clock for process id with both components set to 0.
+func NewHLC(id int) *HLC {
+	return &HLC{id, 0, 0}
+}
+
+// Now updates the clock for a local or send event that happens when the process's physical clock reads pt.
+func (clock *HLC) Now(pt int64) {
+	previous := clock.Physical
+	if pt > clock.Physical {
+		clock.Physical = pt
+	}
+	if clock.Physical == previous {
+		clock.Logical++
+	} else {
+		clock.Logical = 0
+	}
+}
+
+// Update updates the clock for the receipt of a message stamped with other, when the process's physical clock reads pt.
+// Unlike VectorClock.Merge, there is no need to call Now as well.
+func (clock *HLC) Update(other *HLC, pt int64) {
+	previous := clock.Physical
+	if other.Physical > clock.Physical {
+		clock.Physical = other.Physical
+	}
+	if pt > clock.Physical {
+		clock.Physical = pt
+	}
+	switch {
+	case clock.Physical == previous && clock.Physical == other.Physical:
+		if other.Logical > clock.Logical {
+			clock.Logical = other.Logical
+		}
+		clock.Logical++
+	case clock.Physical == previous:
+		clock.Logical++
+	case clock.Physical == other.Physical:
+		clock.Logical = other.Logical + 1
+	default:
+		clock.Logical = 0
+	}
+}
+
+// Less reports whether clock comes before other, comparing the physical components and then the logical components.
+func (clock *HLC) Less(other *HLC) bool {
+	if clock.Physical != other.Physical {
+		return clock.Physical < other.Physical
+	}
+	return clock.Logical < other.Logical
+}
+
+// Copy returns a copy of the hybrid logical clock.
+func (clock *HLC) Copy() *HLC {
+	return &HLC{clock.ID, clock.Physical, clock.Logical}
+}
+
+// String returns the clock in the form (physical,logical).
+func (clock *HLC) String() string {
+	return fmt.Sprintf("(%d,%d)", clock.Physical, clock.Logical)
+}
@@ -0,0 +1,47 @@
+package vclock
+
+import (
+	"testing"
+)
+
+// Tests that the logical component orders events when the physical clock does not advance.
+func TestHLCNow(t *testing.T) {
+	clock := NewHLC(0)
+	clock.Now(5)
+	clock.Now(5)
+	if clock.String() != "(5,1)" {
+		t.Errorf("Expected (5,1) after two events at physical time 5, but got %v", clock)
+	}
+	clock.Now(7)
+	if clock.String() != "(7,0)" {
+		t.Errorf("Expected (7,0) after the physical clock advanced, but got %v", clock)
+	}
+	// A physical clock going backwards does not move the clock backwards.
+	clock.Now(6)
+	if clock.String() != "(7,1)" {
+		t.Errorf("Expected (7,1) when the physical clock reads less, but got %v", clock)
+	}
+}
+
+// Tests that a receive is ordered after the send, even when the receiver's physical clock is behind.
+func TestHLCUpdate(t *testing.T) {
+	sender := NewHLC(0)
+	receiver := NewHLC(1)
+	sender.Now(10)
+	sender.Now(10)
+	receiver.Now(3)
+
+	message := sender.Copy()
+	receiver.Update(message, 4)
+	if receiver.String() != "(10,2)" {
+		t.Errorf("Expected the receive to be (10,2), but got %v", receiver)
+	}
+	if !message.Less(receiver) {
+		t.Errorf("Expected the send %v to come before the receive %v, but it did not.", message, receiver)
+	}
+
+	receiver.Update(&HLC{0, 12, 0}, 12)
+	if receiver.String() != "(12,1)" {
+		t.Errorf("Expected (12,1) when the message and physical clock agree, but got %v", receiver)
+	}
+}
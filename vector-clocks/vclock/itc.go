@@ -0,0 +1,266 @@
+package vclock
+
+import (
+	"fmt"
+)
+
+// ITC represents an interval tree clock stamp, made up of an id tree and an event tree. Unlike vector clocks,
+// interval tree clocks do not need a fixed set of process ids: a stamp can be forked in two to create a new
+// process, and two stamps can be joined back into one when a process retires.
+// Stamps are immutable, every operation returns a new stamp.
+type ITC struct {
+	id    *itcID
+	event *itcEvent
+}
+
+// An id tree, either a leaf with value 0 or 1, or a node with two subtrees splitting the interval in half.
+type itcID struct {
+	value       int
+	left, right *itcID
+}
+
+// An event tree, a counter n for the whole interval plus, for non-leaf trees, the event trees of each half.
+type itcEvent struct {
+	n           int
+	left, right *itcEvent
+}
+
+// The large cost added when growing an event tree requires expanding a leaf, so that it is only done when needed.
+const itcExpandCost = 1 << 20
+
+var (
+	itcZero = &itcID{value: 0}
+	itcOne  = &itcID{value: 1}
+)
+
+// SeedITC returns the seed stamp, which owns the whole interval and has seen no events.
+func SeedITC() *ITC {
+	return &ITC{itcOne, &itcEvent{n: 0}}
+}
+
+// Fork splits the stamp into two stamps with the same events but disjoint ids, one for each process.
+func (stamp *ITC) Fork() (*ITC, *ITC) {
+	left, right := stamp.id.split()
+	return &ITC{left, stamp.event}, &ITC{right, stamp.event}
+}
+
+// Join merges two stamps into one that owns both ids and has seen the events of both.
+func (stamp *ITC) Join(other *ITC) *ITC {
+	return &ITC{sumIDs(stamp.id, other.id), joinEvents(stamp.event, other.event)}
+}
+
+// Event returns the stamp after recording a new event, inflating the event tree within the stamp's id.
+func (stamp *ITC) Event() *ITC {
+	filled := fill(stamp.id, stamp.event)
+	if !filled.equal(stamp.event) {
+		return &ITC{stamp.id, filled}
+	}
+	grown, _ := grow(stamp.id, stamp.event)
+	return &ITC{stamp.id, grown}
+}
+
+// Peek returns an anonymous stamp with the same events but no id, used to send the stamp in a message.
+func (stamp *ITC) Peek() *ITC {
+	return &ITC{itcZero, stamp.event}
+}
+
+// Leq reports whether every event seen by stamp has also been seen by other.
+func (stamp *ITC) Leq(other *ITC) bool {
+	return leq(stamp.event, other.event)
+}
+
+// String returns the stamp in the form (id, event), where trees are written as (left,right) and (n,left,right).
+func (stamp *ITC) String() string {
+	return fmt.Sprintf("(%v, %v)", stamp.id, stamp.event)
+}
+
+func (id *itcID) leaf() bool {
+	return id.left == nil
+}
+
+func (id *itcID) String() string {
+	if id.leaf() {
+		return fmt.Sprint(id.value)
+	}
+	return fmt.Sprintf("(%v,%v)", id.left, id.right)
+}
+
+// Returns the id tree with the given subtrees, collapsing (0,0) and (1,1) into leaves.
+func newID(left *itcID, right *itcID) *itcID {
+	if left.leaf() && right.leaf() && left.value == right.value {
+		return left
+	}
+	return &itcID{left: left, right: right}
+}
+
+// Splits an id into two disjoint ids whose sum is the original.
+func (id *itcID) split() (*itcID, *itcID) {
+	switch {
+	case id.leaf() && id.value == 0:
+		return itcZero, itcZero
+	case id.leaf():
+		return newID(itcOne, itcZero), newID(itcZero, itcOne)
+	case id.left.leaf() && id.left.value == 0:
+		a, b := id.right.split()
+		return newID(itcZero, a), newID(itcZero, b)
+	case id.right.leaf() && id.right.value == 0:
+		a, b := id.left.split()
+		return newID(a, itcZero), newID(b, itcZero)
+	}
+	return newID(id.left, itcZero), newID(itcZero, id.right)
+}
+
+// Returns the union of two disjoint ids.
+func sumIDs(a *itcID, b *itcID) *itcID {
+	switch {
+	case a.leaf() && a.value == 0:
+		return b
+	case b.leaf() && b.value == 0:
+		return a
+	case a.leaf() || b.leaf():
+		// Disjoint ids can only both be non-zero if both are split.
+		return itcOne
+	}
+	return newID(sumIDs(a.left, b.left), sumIDs(a.right, b.right))
+}
+
+func (e *itcEvent) leaf() bool {
+	return e.left == nil
+}
+
+func (e *itcEvent) String() string {
+	if e.leaf() {
+		return fmt.Sprint(e.n)
+	}
+	return fmt.Sprintf("(%d,%v,%v)", e.n, e.left, e.right)
+}
+
+func (e *itcEvent) equal(other *itcEvent) bool {
+	if e.leaf() || other.leaf() {
+		return e.leaf() && other.leaf() && e.n == other.n
+	}
+	return e.n == other.n && e.left.equal(other.left) && e.right.equal(other.right)
+}
+
+// Returns the event tree with m added to its counter.
+func (e *itcEvent) lift(m int) *itcEvent {
+	return &itcEvent{e.n + m, e.left, e.right}
+}
+
+// Returns the smallest count in the event tree. Event trees are kept normalized, so this is the root's counter.
+func (e *itcEvent) min() int {
+	return e.n
+}
+
+// Returns the largest count in the event tree.
+func (e *itcEvent) max() int {
+	if e.leaf() {
+		return e.n
+	}
+	left, right := e.left.max(), e.right.max()
+	if left > right {
+		return e.n + left
+	}
+	return e.n + right
+}
+
+// Returns the normalized event tree for counter n and the given subtrees, moving any count shared by both
+// subtrees up into the counter.
+func newEvent(n int, left *itcEvent, right *itcEvent) *itcEvent {
+	if left.leaf() && right.leaf() && left.n == right.n {
+		return &itcEvent{n: n + left.n}
+	}
+	m := left.min()
+	if right.min() < m {
+		m = right.min()
+	}
+	return &itcEvent{n + m, left.lift(-m), right.lift(-m)}
+}
+
+// Returns the event tree that has seen every event in a and b.
+func joinEvents(a *itcEvent, b *itcEvent) *itcEvent {
+	if a.leaf() && b.leaf() {
+		if a.n > b.n {
+			return a
+		}
+		return b
+	}
+	if a.leaf() {
+		a = &itcEvent{a.n, &itcEvent{n: 0}, &itcEvent{n: 0}}
+	}
+	if b.leaf() {
+		b = &itcEvent{b.n, &itcEvent{n: 0}, &itcEvent{n: 0}}
+	}
+	if a.n > b.n {
+		a, b = b, a
+	}
+	d := b.n - a.n
+	return newEvent(a.n, joinEvents(a.left, b.left.lift(d)), joinEvents(a.right, b.right.lift(d)))
+}
+
+// Reports whether every count in a is less than or equal to the corresponding count in b.
+func leq(a *itcEvent, b *itcEvent) bool {
+	if a.n > b.n {
+		return false
+	}
+	if a.leaf() {
+		return true
+	}
+	if b.leaf() {
+		return leq(a.left.lift(a.n), b) && leq(a.right.lift(a.n), b)
+	}
+	return leq(a.left.lift(a.n), b.left.lift(b.n)) && leq(a.right.lift(a.n), b.right.lift(b.n))
+}
+
+// Inflates the event tree as much as possible within the intervals owned by id, without growing the tree.
+func fill(id *itcID, e *itcEvent) *itcEvent {
+	switch {
+	case id.leaf() && id.value == 0:
+		return e
+	case id.leaf():
+		return &itcEvent{n: e.max()}
+	case e.leaf():
+		return e
+	case id.left.leaf() && id.left.value == 1:
+		right := fill(id.right, e.right)
+		left := e.left.max()
+		if right.min() > left {
+			left = right.min()
+		}
+		return newEvent(e.n, &itcEvent{n: left}, right)
+	case id.right.leaf() && id.right.value == 1:
+		left := fill(id.left, e.left)
+		right := e.right.max()
+		if left.min() > right {
+			right = left.min()
+		}
+		return newEvent(e.n, left, &itcEvent{n: right})
+	}
+	return newEvent(e.n, fill(id.left, e.left), fill(id.right, e.right))
+}
+
+// Inflates the event tree within the intervals owned by id by adding to a single counter, preferring the change
+// that expands the tree the least. Returns the new tree and the cost of the change.
+func grow(id *itcID, e *itcEvent) (*itcEvent, int) {
+	if e.leaf() {
+		if id.leaf() && id.value == 1 {
+			return &itcEvent{n: e.n + 1}, 0
+		}
+		grown, cost := grow(id, &itcEvent{e.n, &itcEvent{n: 0}, &itcEvent{n: 0}})
+		return grown, cost + itcExpandCost
+	}
+	switch {
+	case id.left.leaf() && id.left.value == 0:
+		right, cost := grow(id.right, e.right)
+		return &itcEvent{e.n, e.left, right}, cost + 1
+	case id.right.leaf() && id.right.value == 0:
+		left, cost := grow(id.left, e.left)
+		return &itcEvent{e.n, left, e.right}, cost + 1
+	}
+	left, leftCost := grow(id.left, e.left)
+	right, rightCost := grow(id.right, e.right)
+	if leftCost < rightCost {
+		return &itcEvent{e.n, left, e.right}, leftCost + 1
+	}
+	return &itcEvent{e.n, e.left, right}, rightCost + 1
+}
@@ -0,0 +1,69 @@
+package vclock
+
+import (
+	"testing"
+)
+
+// Tests a run with forks, events and a join, in the style of the example from the interval tree clocks paper.
+func TestITCExample(t *testing.T) {
+	a, b := SeedITC().Fork()
+	a = a.Event()
+	b = b.Event()
+	a1, a2 := a.Fork()
+	b = b.Event()
+	a2 = a2.Event()
+	b = b.Join(a2)
+
+	if a1.String() != "(((1,0),0), (0,1,0))" {
+		t.Errorf("Expected a1 to be (((1,0),0), (0,1,0)), but got %v", a1)
+	}
+	if b.String() != "(((0,1),1), (1,(0,0,1),1))" {
+		t.Errorf("Expected b to be (((0,1),1), (1,(0,0,1),1)), but got %v", b)
+	}
+	// b has seen everything a1 has, but not the other way round.
+	if !a1.Leq(b) || b.Leq(a1) {
+		t.Errorf("Expected %v to happen before %v, but it did not.", a1, b)
+	}
+	// Joining every stamp back together gives the whole interval again.
+	whole := a1.Join(b)
+	if whole.id.String() != "1" {
+		t.Errorf("Expected the joined stamps to own the whole interval, but got %v", whole)
+	}
+}
+
+// Tests that stamps that recorded events independently are concurrent, and that a message orders them.
+func TestITCConcurrent(t *testing.T) {
+	a, b := SeedITC().Fork()
+	a = a.Event()
+	b = b.Event()
+
+	if a.Leq(b) || b.Leq(a) {
+		t.Errorf("Expected %v and %v to be concurrent, but they were not.", a, b)
+	}
+	// b receives a message from a.
+	message := a.Peek()
+	b = b.Join(message).Event()
+	if !a.Leq(b) || b.Leq(a) {
+		t.Errorf("Expected %v to happen before %v, but it did not.", a, b)
+	}
+	if message.id.String() != "0" {
+		t.Errorf("Expected a peeked stamp to have no id, but got %v", message)
+	}
+	if a.String() != "((1,0), (0,1,0))" || b.String() != "((0,1), (1,0,1))" {
+		t.Errorf("Expected stamps ((1,0), (0,1,0)) and ((0,1), (1,0,1)), but got %v and %v", a, b)
+	}
+}
+
+// Tests that a process that retired by joining leaves no trace of its id.
+func TestITCJoinRetires(t *testing.T) {
+	a, b := SeedITC().Fork()
+	b = b.Event().Event()
+	a = a.Join(b)
+	if a.String() != "(1, (0,0,2))" {
+		t.Errorf("Expected (1, (0,0,2)), but got %v", a)
+	}
+	// With the whole interval, the next event can fill the tree.
+	if a.Event().String() != "(1, 2)" {
+		t.Errorf("Expected the next event to fill the tree to (1, 2), but got %v", a.Event())
+	}
+}
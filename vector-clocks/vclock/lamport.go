@@ -0,0 +1,50 @@
+package vclock
+
+import (
+	"fmt"
+)
+
+// LamportClock represents a Lamport scalar clock. Lamport timestamps are consistent with happens-before
+// (if a happens before b, a's timestamp is smaller), but unlike vector clocks they also order concurrent events.
+type LamportClock struct {
+	// The process id that the clock belongs to, used to break ties between equal times.
+	ID int
+	// The scalar time of the clock.
+	Time int
+}
+
+// NewLamport returns a Lamport clock for process id with its time set to 0.
+func NewLamport(id int) *LamportClock {
+	return &LamportClock{id, 0}
+}
+
+// Tick increments the clock's time.
+func (clock *LamportClock) Tick() {
+	clock.Time++
+}
+
+// Merge sets the clock's time to the max of its own time and other's. Like VectorClock.Merge, it does not tick the clock.
+func (clock *LamportClock) Merge(other *LamportClock) {
+	if clock.Time < other.Time {
+		clock.Time = other.Time
+	}
+}
+
+// Less reports whether clock comes before other in the total order of Lamport timestamps, where ties between
+// equal times are broken by process id.
+func (clock *LamportClock) Less(other *LamportClock) bool {
+	if clock.Time != other.Time {
+		return clock.Time < other.Time
+	}
+	return clock.ID < other.ID
+}
+
+// Copy returns a copy of the Lamport clock.
+func (clock *LamportClock) Copy() *LamportClock {
+	return &LamportClock{clock.ID, clock.Time}
+}
+
+// String returns the clock in the form (time,id).
+func (clock *LamportClock) String() string {
+	return fmt.Sprintf("(%d,%d)", clock.Time, clock.ID)
+}
@@ -0,0 +1,31 @@
+package vclock
+
+import (
+	"testing"
+)
+
+// Tests that a receive is stamped after the send and that ties are broken by process id.
+func TestLamportOrder(t *testing.T) {
+	a := NewLamport(0)
+	b := NewLamport(1)
+	a.Tick()
+	b.Tick()
+
+	if !a.Less(b) || b.Less(a) {
+		t.Errorf("Expected %v to come before %v when tied, but it did not.", a, b)
+	}
+
+	message := a.Copy()
+	a.Tick()
+	b.Merge(message)
+	b.Tick()
+	if b.String() != "(2,1)" {
+		t.Errorf("Expected the receive to be stamped (2,1), but got %v", b)
+	}
+	if !message.Less(b) {
+		t.Errorf("Expected the send %v to come before the receive %v, but it did not.", message, b)
+	}
+	if !a.Less(b) {
+		t.Errorf("Expected %v to come before %v, but it did not.", a, b)
+	}
+}
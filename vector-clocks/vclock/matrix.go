@@ -0,0 +1,88 @@
+package vclock
+
+import (
+	"strings"
+)
+
+// MatrixClock represents a matrix clock, which tracks what a process knows about every other process's vector clock.
+// Row i of the matrix kept at process i is its own vector clock, and row j is the latest vector clock of process j
+// that process i has heard of.
+type MatrixClock struct {
+	// The process id that the matrix clock belongs to.
+	ID int
+	// Stores the vector clock known for each process.
+	Rows [][]int
+}
+
+// NewMatrix returns a matrix clock for process id in a system of n processes, with every counter set to 0.
+func NewMatrix(id int, n int) *MatrixClock {
+	rows := make([][]int, n)
+	for i := range rows {
+		rows[i] = make([]int, n)
+	}
+	return &MatrixClock{id, rows}
+}
+
+// Tick increments the owning process's counter in its own row.
+func (clock *MatrixClock) Tick() {
+	clock.Rows[clock.ID][clock.ID]++
+}
+
+// Merge updates the clock with a matrix clock received from another process. The sender's row is merged into
+// the owning process's row, and every entry is set to the max of its own value and the one in other.
+// Like VectorClock.Merge, it does not tick the clock.
+func (clock *MatrixClock) Merge(other *MatrixClock) {
+	for j, counter := range other.Rows[other.ID] {
+		if clock.Rows[clock.ID][j] < counter {
+			clock.Rows[clock.ID][j] = counter
+		}
+	}
+	for i, row := range other.Rows {
+		for j, counter := range row {
+			if clock.Rows[i][j] < counter {
+				clock.Rows[i][j] = counter
+			}
+		}
+	}
+}
+
+// Vector returns the owning process's vector clock, its row of the matrix.
+func (clock *MatrixClock) Vector() *VectorClock {
+	counters := make([]int, len(clock.Rows[clock.ID]))
+	copy(counters, clock.Rows[clock.ID])
+	return &VectorClock{clock.ID, counters}
+}
+
+// MinKnown returns, for each process j, the number of j's events that the owning process knows every process
+// has heard of. Anything process j sent at or before that event can safely be garbage-collected.
+func (clock *MatrixClock) MinKnown() []int {
+	known := make([]int, len(clock.Rows))
+	for j := range known {
+		known[j] = clock.Rows[0][j]
+		for _, row := range clock.Rows {
+			if row[j] < known[j] {
+				known[j] = row[j]
+			}
+		}
+	}
+	return known
+}
+
+// Copy returns a copy of the matrix clock that does not share its rows with the original.
+func (clock *MatrixClock) Copy() *MatrixClock {
+	rows := make([][]int, len(clock.Rows))
+	for i, row := range clock.Rows {
+		rows[i] = make([]int, len(row))
+		copy(rows[i], row)
+	}
+	return &MatrixClock{clock.ID, rows}
+}
+
+// String returns the rows of the matrix in the form [[a b] [c d]].
+func (clock *MatrixClock) String() string {
+	rows := make([]string, len(clock.Rows))
+	for i, row := range clock.Rows {
+		rows[i] = FormatCounters(row)
+	}
+	return "[" + strings.Join(rows, " ") + "]"
+}
@@ -0,0 +1,32 @@
+package vclock
+
+import (
+	"testing"
+)
+
+// Tests that a process learns what the sender knows about every other process when it receives a matrix clock.
+func TestMatrixMerge(t *testing.T) {
+	a := NewMatrix(0, 3)
+	b := NewMatrix(1, 3)
+	c := NewMatrix(2, 3)
+
+	// a sends to b, then b sends to c.
+	a.Tick()
+	b.Merge(a.Copy())
+	b.Tick()
+	b.Tick()
+	c.Merge(b.Copy())
+	c.Tick()
+
+	if c.String() != "[[1 0 0] [1 2 0] [1 2 1]]" {
+		t.Errorf("Expected c to be [[1 0 0] [1 2 0] [1 2 1]], but got %v", c)
+	}
+	if c.Vector().String() != "[1 2 1]" {
+		t.Errorf("Expected c's vector clock to be its own row [1 2 1], but got %v", c.Vector())
+	}
+	// c does not know that a has heard of anything from b or c.
+	known := FormatCounters(c.MinKnown())
+	if known != "[1 0 0]" {
+		t.Errorf("Expected c to know every process has seen a's first event, [1 0 0], but got %v", known)
+	}
+}
@@ -0,0 +1,50 @@
+package vclock
+
+// PlausibleClock represents a plausible clock with R entries, a vector clock of fixed size where process i ticks
+// entry i mod R. Processes that share an entry cannot be told apart, so a plausible clock never misses a
+// happened-before relation, but it can report concurrent events as ordered. With as many entries as processes it
+// is an ordinary vector clock.
+type PlausibleClock struct {
+	// The process id that the clock belongs to.
+	ID int
+	// Stores the counter of each entry.
+	Counters []int
+}
+
+// NewPlausible returns a plausible clock with r entries for process id, with every counter set to 0.
+func NewPlausible(id int, r int) *PlausibleClock {
+	return &PlausibleClock{id, make([]int, r)}
+}
+
+// Tick increments the counter of the owning process's entry.
+func (clock *PlausibleClock) Tick() {
+	clock.Counters[clock.ID%len(clock.Counters)]++
+}
+
+// Merge sets every counter to the max of its own value and the corresponding value in other.
+// Like VectorClock.Merge, it does not tick the clock.
+func (clock *PlausibleClock) Merge(other *PlausibleClock) {
+	for i, counter := range other.Counters {
+		if clock.Counters[i] < counter {
+			clock.Counters[i] = counter
+		}
+	}
+}
+
+// Compare reports how clock relates to other in the same way as VectorClock.Compare. Before and After may be
+// reported for concurrent events, but never Concurrent for ordered ones.
+func (clock *PlausibleClock) Compare(other *PlausibleClock) Ordering {
+	return CompareCounters(clock.Counters, other.Counters)
+}
+
+// Copy returns a copy of the plausible clock that does not share its counters with the original.
+func (clock *PlausibleClock) Copy() *PlausibleClock {
+	counters := make([]int, len(clock.Counters))
+	copy(counters, clock.Counters)
+	return &PlausibleClock{clock.ID, counters}
+}
+
+// String returns the counters of the clock in the form [a b c].
+func (clock *PlausibleClock) String() string {
+	return FormatCounters(clock.Counters)
+}
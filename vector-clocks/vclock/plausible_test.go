@@ -0,0 +1,28 @@
+package vclock
+
+import (
+	"testing"
+)
+
+// Tests that processes sharing an entry of a plausible clock make concurrent events look ordered.
+func TestPlausibleSharedEntry(t *testing.T) {
+	// Processes 0 and 2 share entry 0.
+	a, b, c := NewPlausible(0, 2), NewPlausible(1, 2), NewPlausible(2, 2)
+	a.Tick()
+	c.Tick()
+	c.Tick()
+	if a.String() != "[1 0]" || c.String() != "[2 0]" {
+		t.Errorf("Expected [1 0] and [2 0] but got %v and %v", a, c)
+	}
+	if a.Compare(c) != Before {
+		t.Errorf("Expected the concurrent events to look ordered but got %v", a.Compare(c))
+	}
+	// A message still orders the send before the receive.
+	message := a.Copy()
+	b.Tick()
+	b.Merge(message)
+	b.Tick()
+	if b.String() != "[1 2]" || message.Compare(b) != Before {
+		t.Errorf("Expected the receive [1 2] to come after the send %v but got %v", message, b)
+	}
+}
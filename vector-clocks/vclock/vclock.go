@@ -0,0 +1,132 @@
+// Package vclock implements vector clocks that can be used to order events across nodes in a distributed system.
+//
+// A VectorClock has no knowledge of how messages are delivered. A node ticks its clock for every local event,
+// ticks and attaches a copy of its clock to every message it sends, and merges the attached clock into its own
+// (then ticks) for every message it receives.
+package vclock
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Ordering describes how two vector clocks relate to each other.
+type Ordering int
+
+const (
+	// Before means the first clock happened before the second.
+	Before Ordering = iota
+	// After means the first clock happened after the second.
+	After
+	// Equal means both clocks have identical counters.
+	Equal
+	// Concurrent means neither clock happened before the other.
+	Concurrent
+)
+
+// String returns the name of the ordering.
+func (o Ordering) String() string {
+	switch o {
+	case Before:
+		return "Before"
+	case After:
+		return "After"
+	case Equal:
+		return "Equal"
+	case Concurrent:
+		return "Concurrent"
+	}
+	return fmt.Sprintf("Ordering(%d)", int(o))
+}
+
+// VectorClock represents the vector clock that is kept at each node to maintain ordering.
+type VectorClock struct {
+	// The process id that the vector clock belongs to.
+	ID int
+	// Stores the counter value for each process.
+	Counters []int
+}
+
+// New returns a vector clock for process id in a system of n processes, with every counter set to 0.
+func New(id int, n int) *VectorClock {
+	return &VectorClock{id, make([]int, n)}
+}
+
+// Tick increments the owning process's counter.
+func (clock *VectorClock) Tick() {
+	clock.grow(clock.ID + 1)
+	clock.Counters[clock.ID]++
+}
+
+// Merge sets every counter to the max of its own value and the corresponding value in other.
+// It does not tick the clock, a receive event should call Tick as well.
+func (clock *VectorClock) Merge(other *VectorClock) {
+	clock.grow(len(other.Counters))
+	for i, counter := range other.Counters {
+		if clock.Counters[i] < counter {
+			clock.Counters[i] = counter
+		}
+	}
+}
+
+// Compare reports whether clock happened before, after, concurrently with, or is equal to other.
+// Clocks of different lengths are compared as if the shorter one were padded with zeros.
+func (clock *VectorClock) Compare(other *VectorClock) Ordering {
+	return CompareCounters(clock.Counters, other.Counters)
+}
+
+// Copy returns a copy of the vector clock that does not share its counters with the original.
+func (clock *VectorClock) Copy() *VectorClock {
+	counters := make([]int, len(clock.Counters))
+	copy(counters, clock.Counters)
+	return &VectorClock{clock.ID, counters}
+}
+
+// String returns the counters of the clock in the form [a b c].
+func (clock *VectorClock) String() string {
+	return FormatCounters(clock.Counters)
+}
+
+// Extends the counters with zeros so there are at least n of them.
+func (clock *VectorClock) grow(n int) {
+	for len(clock.Counters) < n {
+		clock.Counters = append(clock.Counters, 0)
+	}
+}
+
+// CompareCounters compares two raw counter slices in the same way as VectorClock.Compare.
+func CompareCounters(a []int, b []int) Ordering {
+	less, greater := false, false
+	for i := 0; i < len(a) || i < len(b); i++ {
+		x, y := 0, 0
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x < y {
+			less = true
+		} else if x > y {
+			greater = true
+		}
+	}
+	switch {
+	case less && greater:
+		return Concurrent
+	case less:
+		return Before
+	case greater:
+		return After
+	}
+	return Equal
+}
+
+// FormatCounters returns the counters in the form [a b c].
+func FormatCounters(counters []int) string {
+	values := make([]string, len(counters))
+	for i, counter := range counters {
+		values[i] = fmt.Sprint(counter)
+	}
+	return "[" + strings.Join(values, " ") + "]"
+}
@@ -0,0 +1,67 @@
+package vclock
+
+import (
+	"testing"
+)
+
+// Tests that a message passed from one clock to another orders the send before the receive.
+func TestSendReceive(t *testing.T) {
+	a := New(0, 3)
+	b := New(1, 3)
+
+	a.Tick()
+	message := a.Copy()
+	b.Tick()
+	b.Merge(message)
+	b.Tick()
+
+	if a.String() != "[1 0 0]" || b.String() != "[1 2 0]" {
+		t.Errorf("Expected clocks [1 0 0] and [1 2 0], but got %v and %v", a, b)
+	}
+	if message.Compare(b) != Before {
+		t.Errorf("Expected the send %v to come before the receive %v, but got %v", message, b, message.Compare(b))
+	}
+	if b.Compare(message) != After {
+		t.Errorf("Expected the receive %v to come after the send %v, but got %v", b, message, b.Compare(message))
+	}
+}
+
+// Tests that clocks with no causal relationship are reported as concurrent.
+func TestConcurrent(t *testing.T) {
+	a := New(0, 2)
+	b := New(1, 2)
+	a.Tick()
+	b.Tick()
+
+	if a.Compare(b) != Concurrent {
+		t.Errorf("Expected %v and %v to be concurrent, but got %v", a, b, a.Compare(b))
+	}
+	if a.Compare(a.Copy()) != Equal {
+		t.Errorf("Expected %v to equal its copy", a)
+	}
+}
+
+// Tests that a copy does not share counters with the original clock.
+func TestCopy(t *testing.T) {
+	a := New(0, 2)
+	c := a.Copy()
+	a.Tick()
+
+	if c.Counters[0] != 0 {
+		t.Errorf("Expected the copy to be unaffected by ticking the original, but got %v", c)
+	}
+}
+
+// Tests that clocks of different lengths are padded with zeros when merged and compared.
+func TestDifferentLengths(t *testing.T) {
+	a := &VectorClock{0, []int{1}}
+	b := &VectorClock{2, []int{0, 0, 1}}
+
+	if a.Compare(b) != Concurrent {
+		t.Errorf("Expected %v and %v to be concurrent, but got %v", a, b, a.Compare(b))
+	}
+	a.Merge(b)
+	if a.String() != "[1 0 1]" {
+		t.Errorf("Expected the merged clock to be [1 0 1], but got %v", a)
+	}
+}